@@ -0,0 +1,82 @@
+package bliss
+
+/*
+  Copyright (c) 2016 Tim Henderson
+  Released under the GNU General Public License version 3.
+
+  This file is part of goiso a wrapper around bliss.
+
+  bliss is free software: you can redistribute it and/or modify
+  it under the terms of the GNU General Public License version 3
+  as published by the Free Software Foundation.
+
+  bliss is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU General Public License for more details.
+
+  You should have received a copy of the GNU General Public License
+  along with goiso.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"fmt"
+)
+
+// Why a search was abandoned before it completed.
+type AbortReason int32
+
+const (
+	Canceled   AbortReason = iota + 1 // the context was canceled
+	TimedOut                          // Options.Timeout ran out
+	NodeBudget                        // Options.MaxNodes ran out
+)
+
+func (r AbortReason) String() string {
+	switch r {
+	case Canceled:
+		return "canceled"
+	case TimedOut:
+		return "timed out"
+	case NodeBudget:
+		return "node budget exhausted"
+	}
+	return fmt.Sprintf("AbortReason(%d)", int32(r))
+}
+
+// Returned by the Context variants (CanonizeContext,
+// Map.CanonicalPermutationContext) when the search was abandoned. No
+// canonical labeling is produced for the graph.
+type AbortError struct {
+	Reason AbortReason
+	// The statistics of the search up to the point it was abandoned. Useful
+	// for logging the graphs which make the search hard. Nil if the search
+	// never started.
+	Stats *Stats
+	// The context's error if the Reason is Canceled.
+	// context.DeadlineExceeded if the Reason is TimedOut.
+	Err error
+}
+
+func (e *AbortError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("bliss: search %v: %v", e.Reason, e.Err)
+	}
+	return fmt.Sprintf("bliss: search %v", e.Reason)
+}
+
+func (e *AbortError) Unwrap() error {
+	return e.Err
+}
+
+func newAbortError(ctx context.Context, reason AbortReason, stats *Stats) *AbortError {
+	e := &AbortError{Reason: reason, Stats: stats}
+	switch reason {
+	case Canceled:
+		e.Err = ctx.Err()
+	case TimedOut:
+		e.Err = context.DeadlineExceeded
+	}
+	return e
+}
@@ -26,6 +26,9 @@
 
 #include <cstdlib>
 #include <cstdio>
+#include <cstring>
+#include <string>
+#include <vector>
 #include "defs.hh"
 
 namespace bliss {
@@ -69,6 +72,19 @@ public:
    * Print the number in the file stream \a fp.
    */
   size_t print(FILE* const fp) const {return mpz_out_str(fp, 10, v); }
+
+  /**
+   * Return the number as a string of decimal digits.
+   */
+  std::string str() const
+  {
+    void (*freefunc)(void *, size_t);
+    char* s = mpz_get_str(0, 10, v);
+    std::string r(s);
+    mp_get_memory_functions(0, 0, &freefunc);
+    freefunc(s, strlen(s)+1);
+    return r;
+  }
 };
 
 #else
@@ -76,26 +92,72 @@ public:
 class BigNum
 {
   long double v;
+  /* The exact value in base 10^9 limbs, least significant limb first.
+   * Only non-negative numbers are tracked exactly. */
+  std::vector<unsigned int> limbs;
+  static const unsigned int base = 1000000000;
 public:
   /**
    * Create a new big number and set it to zero.
    */
-  BigNum(): v(0.0) {}
+  BigNum(): v(0.0), limbs(1, 0) {}
 
   /**
    * Set the number to \a n.
    */
-  void assign(const int n) {v = (long double)n; }
+  void assign(const int n)
+  {
+    unsigned long long x = n < 0 ? 0 : (unsigned long long)n;
+    v = (long double)n;
+    limbs.clear();
+    do {
+      limbs.push_back((unsigned int)(x % base));
+      x /= base;
+    } while(x > 0);
+  }
 
   /**
    * Multiply the number with \a n.
    */
-  void multiply(const int n) {v *= (long double)n; }
+  void multiply(const int n)
+  {
+    unsigned long long carry = 0;
+    v *= (long double)n;
+    if(n <= 0) {
+      limbs.assign(1, 0);
+      return;
+    }
+    for(unsigned int i = 0; i < limbs.size(); i++) {
+      unsigned long long cur = (unsigned long long)limbs[i] * n + carry;
+      limbs[i] = (unsigned int)(cur % base);
+      carry = cur / base;
+    }
+    while(carry > 0) {
+      limbs.push_back((unsigned int)(carry % base));
+      carry /= base;
+    }
+  }
 
   /**
    * Print the number in the file stream \a fp.
    */
   size_t print(FILE* const fp) const {return fprintf(fp, "%Lg", v); }
+
+  /**
+   * Return the exact number as a string of decimal digits.
+   */
+  std::string str() const
+  {
+    char buf[16];
+    std::string r;
+    snprintf(buf, sizeof(buf), "%u", limbs[limbs.size()-1]);
+    r += buf;
+    for(unsigned int i = limbs.size()-1; i > 0; i--) {
+      snprintf(buf, sizeof(buf), "%09u", limbs[i-1]);
+      r += buf;
+    }
+    return r;
+  }
 };
 
 #endif
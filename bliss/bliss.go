@@ -1,3 +1,5 @@
+//go:build cgo && !purego
+
 package bliss
 
 /*
@@ -26,18 +28,17 @@ package bliss
 import "C"
 
 import (
+	"context"
 	"fmt"
+	"math/big"
 	"reflect"
+	"sync/atomic"
+	"time"
 	"unsafe"
 )
 
 type Digraph C.struct_bliss_graph_struct
 
-type BlissEdge struct {
-	Src  uint32
-	Targ uint32
-}
-
 // Construct and compute the canonical permutation of a digraph. Saves many cgo
 // calls versus using the *Digraph type if your goal is to only compute the
 // canonical permutation of the graph. Read the returned slice as:
@@ -45,25 +46,303 @@ type BlissEdge struct {
 //     mapping[original-index-for-v] -> new-index-for-v
 //
 func Canonize(nodes []uint32, edges []BlissEdge) (mapping []uint) {
+	mapping, _ = canonize(nodes, edges, true, nil, false)
+	return mapping
+}
+
+// The same as Canonize but additionally returns the statistics of the search.
+func CanonizeStats(nodes []uint32, edges []BlissEdge) (mapping []uint, stats *Stats) {
+	return canonize(nodes, edges, true, nil, true)
+}
+
+// The same as CanonizeStats but the search is tuned with the given options.
+// If opts is nil the default options are used.
+func CanonizeOpts(nodes []uint32, edges []BlissEdge, opts *Options) (mapping []uint, stats *Stats) {
+	return canonize(nodes, edges, true, opts, true)
+}
+
+// The same as Canonize except the edges are undirected.
+func CanonizeUndirected(nodes []uint32, edges []BlissEdge) (mapping []uint) {
+	mapping, _ = canonize(nodes, edges, false, nil, false)
+	return mapping
+}
+
+// stats is only computed if withStats is true
+func canonize(nodes []uint32, edges []BlissEdge, directed bool, opts *Options, withStats bool) (mapping []uint, stats *Stats) {
+	mapping, stats, err := constructAndCanonize(nodes, edges, directed, cOptions(opts), nil, withStats)
+	if err != 0 {
+		panic(fmt.Errorf("bliss_construct_and_canonize failed error number = %v", err))
+	}
+	return mapping, stats
+}
+
+// Construct and compute the canonical permutation of a digraph. The search
+// stops early, returning an *AbortError, if the context is canceled or one of
+// the budgets (MaxNodes, Timeout) given in the options runs out. Use this
+// instead of Canonize when a single pathological graph must not stall the
+// caller.
+func CanonizeContext(ctx context.Context, nodes []uint32, edges []BlissEdge, opts *Options) (mapping []uint, stats *Stats, err error) {
+	return canonizeContext(ctx, nodes, edges, true, opts)
+}
+
+func canonizeContext(ctx context.Context, nodes []uint32, edges []BlissEdge, directed bool, opts *Options) (mapping []uint, stats *Stats, err error) {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+	if err := opts.validate(); err != nil {
+		return nil, nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, newAbortError(ctx, Canceled, nil)
+	}
+	copts := cOptions(opts)
+	copts.max_nodes = C.ulong(opts.MaxNodes)
+	// the flag lives in Go memory so the callbacks below may safely fire
+	// after the search has returned.
+	abort := new(C.int)
+	flag := (*int32)(unsafe.Pointer(abort))
+	stop := context.AfterFunc(ctx, func() {
+		atomic.CompareAndSwapInt32(flag, 0, int32(Canceled))
+	})
+	defer stop()
+	if opts.Timeout > 0 {
+		timer := time.AfterFunc(opts.Timeout, func() {
+			atomic.CompareAndSwapInt32(flag, 0, int32(TimedOut))
+		})
+		defer timer.Stop()
+	}
+	mapping, stats, code := constructAndCanonize(nodes, edges, directed, copts, abort, true)
+	if code == 5 {
+		reason := AbortReason(atomic.LoadInt32(flag))
+		if reason == 0 {
+			reason = NodeBudget
+		}
+		return nil, nil, newAbortError(ctx, reason, stats)
+	} else if code != 0 {
+		return nil, nil, fmt.Errorf("bliss_construct_and_canonize failed error number = %v", code)
+	}
+	return mapping, stats, nil
+}
+
+// Calls bliss_construct_and_canonize. On an error code other than 5 (the
+// search was abandoned) mapping and stats are nil.
+func constructAndCanonize(nodes []uint32, edges []BlissEdge, directed bool, opts *C.BlissOptions, abort *C.int, withStats bool) (mapping []uint, stats *Stats, err C.int) {
+	var s *C.BlissStats
+	if withStats {
+		s = new(C.BlissStats)
+	}
 	perm := make([]C.uint, len(nodes))
 	nodes_hdr := (*reflect.SliceHeader)(unsafe.Pointer(&nodes))
 	edges_hdr := (*reflect.SliceHeader)(unsafe.Pointer(&edges))
 	perm_hdr := (*reflect.SliceHeader)(unsafe.Pointer(&perm))
-	err := C.bliss_construct_and_canonize(
+	err = C.bliss_construct_and_canonize(
 		(*C.uint)(unsafe.Pointer(nodes_hdr.Data)),
 		C.int(len(nodes)),
 		(*C.BlissEdge)(unsafe.Pointer(edges_hdr.Data)),
 		C.int(len(edges)),
+		cbool(directed),
+		opts,
+		abort,
 		(*C.uint)(unsafe.Pointer(perm_hdr.Data)),
+		s,
 	)
+	if withStats && (err == 0 || err == 5) {
+		stats = newStats(s)
+	}
 	if err != 0 {
-		panic(fmt.Errorf("bliss_construct_and_canonize failed error number = %v", err))
+		return nil, stats, err
 	}
 	mapping = make([]uint, 0, len(nodes))
 	for i := 0; i < len(nodes); i++ {
 		mapping = append(mapping, uint(perm[i]))
 	}
-	return mapping
+	return mapping, stats, 0
+}
+
+// Construct and compute the canonical permutations of many graphs with a
+// single cgo call. Each graph is still constructed and searched on its own so
+// the batch only saves the cgo crossings and the per graph allocations, which
+// is worth little unless the graphs are tiny. The mappings are read exactly
+// like the mapping returned by Canonize:
+//
+//     mappings[i][original-index-for-v] -> new-index-for-v of graphs[i]
+//
+// A graph which can not be canonized (for instance one without vertices)
+// does not fail the others. errs is nil if every graph was canonized,
+// otherwise errs[i] is non-nil and mappings[i] is nil for each graph which
+// failed.
+func CanonizeBatch(graphs []BatchGraph) (mappings [][]uint, errs []error) {
+	return CanonizeBatchOpts(graphs, nil)
+}
+
+// The same as CanonizeBatch but every search is tuned with the given
+// options. If opts is nil the default options are used. The budgets in the
+// options are not honored.
+func CanonizeBatchOpts(graphs []BatchGraph, opts *Options) (mappings [][]uint, errs []error) {
+	if len(graphs) == 0 {
+		return nil, nil
+	}
+	lenNodes := 0
+	lenEdges := 0
+	for i := range graphs {
+		lenNodes += len(graphs[i].Nodes)
+		lenEdges += len(graphs[i].Edges)
+	}
+	nodes := make([]uint32, 0, lenNodes)
+	edges := make([]BlissEdge, 0, lenEdges)
+	lens := make([]C.int, 0, 3*len(graphs))
+	for i := range graphs {
+		g := &graphs[i]
+		nodes = append(nodes, g.Nodes...)
+		edges = append(edges, g.Edges...)
+		lens = append(lens, C.int(len(g.Nodes)), C.int(len(g.Edges)), cbool(!g.Undirected))
+	}
+	perm := make([]C.uint, lenNodes)
+	status := make([]C.int, len(graphs))
+	nodes_hdr := (*reflect.SliceHeader)(unsafe.Pointer(&nodes))
+	edges_hdr := (*reflect.SliceHeader)(unsafe.Pointer(&edges))
+	lens_hdr := (*reflect.SliceHeader)(unsafe.Pointer(&lens))
+	perm_hdr := (*reflect.SliceHeader)(unsafe.Pointer(&perm))
+	status_hdr := (*reflect.SliceHeader)(unsafe.Pointer(&status))
+	err := C.bliss_construct_and_canonize_batch(
+		(*C.uint)(unsafe.Pointer(nodes_hdr.Data)),
+		(*C.BlissEdge)(unsafe.Pointer(edges_hdr.Data)),
+		(*C.int)(unsafe.Pointer(lens_hdr.Data)),
+		C.int(len(graphs)),
+		cOptions(opts),
+		(*C.uint)(unsafe.Pointer(perm_hdr.Data)),
+		(*C.int)(unsafe.Pointer(status_hdr.Data)),
+	)
+	if err != 0 {
+		panic(fmt.Errorf("bliss_construct_and_canonize_batch failed error number = %v", err))
+	}
+	all := make([]uint, lenNodes)
+	for i, p := range perm {
+		all[i] = uint(p)
+	}
+	mappings = make([][]uint, 0, len(graphs))
+	off := 0
+	for i := range graphs {
+		n := len(graphs[i].Nodes)
+		if status[i] != 0 {
+			if errs == nil {
+				errs = make([]error, len(graphs))
+			}
+			errs[i] = batchError(i, n, int(status[i]))
+			mappings = append(mappings, nil)
+		} else {
+			mappings = append(mappings, all[off:off+n:off+n])
+		}
+		off += n
+	}
+	return mappings, errs
+}
+
+func batchError(i, n, status int) error {
+	if n == 0 {
+		return fmt.Errorf("bliss: cannot canonize a graph with no vertices (graph %v of the batch)", i)
+	}
+	return fmt.Errorf("bliss_construct_and_canonize failed on graph %v error number = %v", i, status)
+}
+
+// Construct and compute a set of generators for the automorphism group of a
+// digraph. Like Canonize this saves many cgo calls versus using the *Digraph
+// type. Read each returned generator as:
+//
+//     aut[v] -> image-of-v
+//
+func Automorphisms(nodes []uint32, edges []BlissEdge) (generators [][]uint) {
+	return automorphisms(nodes, edges, true, nil)
+}
+
+// The same as Automorphisms except the edges are undirected.
+func AutomorphismsUndirected(nodes []uint32, edges []BlissEdge) (generators [][]uint) {
+	return automorphisms(nodes, edges, false, nil)
+}
+
+func automorphisms(nodes []uint32, edges []BlissEdge, directed bool, opts *Options) (generators [][]uint) {
+	if len(nodes) == 0 {
+		return nil
+	}
+	var gens *C.uint
+	var nofGens C.uint
+	nodes_hdr := (*reflect.SliceHeader)(unsafe.Pointer(&nodes))
+	edges_hdr := (*reflect.SliceHeader)(unsafe.Pointer(&edges))
+	err := C.bliss_construct_and_find_automorphisms(
+		(*C.uint)(unsafe.Pointer(nodes_hdr.Data)),
+		C.int(len(nodes)),
+		(*C.BlissEdge)(unsafe.Pointer(edges_hdr.Data)),
+		C.int(len(edges)),
+		cbool(directed),
+		cOptions(opts),
+		&gens,
+		&nofGens,
+	)
+	if err != 0 {
+		panic(fmt.Errorf("bliss_construct_and_find_automorphisms failed error number = %v", err))
+	}
+	return copyGenerators(gens, int(nofGens), len(nodes))
+}
+
+// copies and frees the generator array allocated by the C side.
+func copyGenerators(gens *C.uint, nofGens, n int) (generators [][]uint) {
+	if gens == nil {
+		return nil
+	}
+	defer C.free(unsafe.Pointer(gens))
+	arr := unsafe.Slice(gens, nofGens*n)
+	generators = make([][]uint, 0, nofGens)
+	for i := 0; i < nofGens; i++ {
+		aut := make([]uint, 0, n)
+		for _, p := range arr[i*n : (i+1)*n] {
+			aut = append(aut, uint(p))
+		}
+		generators = append(generators, aut)
+	}
+	return generators
+}
+
+func cbool(b bool) C.int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Converts the options into C. A nil opts stays nil so bliss uses its
+// defaults.
+func cOptions(opts *Options) *C.BlissOptions {
+	if opts == nil {
+		return nil
+	}
+	if err := opts.validate(); err != nil {
+		panic(err)
+	}
+	return &C.BlissOptions{
+		splitting_heuristic: C.uint(opts.SplittingHeuristic),
+		component_recursion: cbool(opts.ComponentRecursion),
+		failure_recording:   cbool(opts.FailureRecording),
+		long_prune:          cbool(opts.LongPrune),
+	}
+}
+
+// Converts the C stats into Go and releases the C side memory.
+func newStats(s *C.BlissStats) *Stats {
+	defer C.bliss_release_stats(s)
+	size, ok := new(big.Int).SetString(C.GoString(s.group_size), 10)
+	if !ok {
+		panic(fmt.Errorf("bliss returned a malformed group size %q", C.GoString(s.group_size)))
+	}
+	return &Stats{
+		GroupSize:       size,
+		GroupSizeApprox: float64(s.group_size_approx),
+		Nodes:           uint64(s.nof_nodes),
+		LeafNodes:       uint64(s.nof_leaf_nodes),
+		BadNodes:        uint64(s.nof_bad_nodes),
+		CanonUpdates:    uint64(s.nof_canupdates),
+		Generators:      uint64(s.nof_generators),
+		MaxLevel:        uint64(s.max_level),
+	}
 }
 
 // A context manager which release the graph after the block
@@ -75,7 +354,7 @@ func Do(nodes int, block func(*Digraph)) {
 }
 
 // Constructs a new bliss digraph object. Note, this
-// is a directed graph.
+// is a directed graph. See NewGraph for undirected graphs.
 // nodes = the number of nodes to add with color 0
 func NewDigraph(nodes int) *Digraph {
 	n := C.uint(uint(nodes))
@@ -89,6 +368,20 @@ func (g *Digraph) Release() {
 	C.bliss_release(G)
 }
 
+// Set the options used by all subsequent searches on the graph (Canonical,
+// CanonicalPermutation, Iso, Automorphisms, ...). A nil opts restores the
+// defaults.
+func (g *Digraph) SetOptions(opts *Options) {
+	setOptions((*C.struct_bliss_graph_struct)(g), opts)
+}
+
+func setOptions(G *C.struct_bliss_graph_struct, opts *Options) {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+	C.bliss_set_options(G, cOptions(opts))
+}
+
 // Add a new vertex of the given color to the graph.
 // The vertex id will be returned.
 func (g *Digraph) AddVertex(color uint) uint {
@@ -152,6 +445,15 @@ func (g *Digraph) Canonical() *Digraph {
 	return (*Digraph)(C.bliss_permute(G, p))
 }
 
+// The same as Canonical but additionally returns the statistics of the
+// search.
+func (g *Digraph) CanonicalStats() (*Digraph, *Stats) {
+	var s C.BlissStats
+	G := (*C.struct_bliss_graph_struct)(g)
+	p := C.bliss_find_canonical_labeling(G, nil, nil, &s)
+	return (*Digraph)(C.bliss_permute(G, p)), newStats(&s)
+}
+
 // A context manager for Canonical graph.
 func (g *Digraph) CanonicalCtx(block func(*Digraph)) {
 	can := g.Canonical()
@@ -164,14 +466,68 @@ func (g *Digraph) CanonicalCtx(block func(*Digraph)) {
 // If you want to preserve the orginal vertex id's or know how the canonical
 // labeling actually maps to the original graph you need to use this method.
 func (g *Digraph) CanonicalPermutation() (mapping []uint) {
-	G := (*C.struct_bliss_graph_struct)(g)
+	return canonicalPermutation((*C.struct_bliss_graph_struct)(g), nil)
+}
+
+// The same as CanonicalPermutation but additionally returns the statistics
+// of the search.
+func (g *Digraph) CanonicalPermutationStats() (mapping []uint, stats *Stats) {
+	var s C.BlissStats
+	mapping = canonicalPermutation((*C.struct_bliss_graph_struct)(g), &s)
+	return mapping, newStats(&s)
+}
+
+func canonicalPermutation(G *C.struct_bliss_graph_struct, stats *C.BlissStats) (mapping []uint) {
 	N := uint(C.bliss_get_nof_vertices(G))
-	p := C.bliss_find_canonical_labeling(G, nil, nil, nil)
+	p := C.bliss_find_canonical_labeling(G, nil, nil, stats)
 	mapping = make([]uint, 0, N)
-	for i := uint(0); i < N; i++ {
-		ptr := (uintptr(unsafe.Pointer(p)) + uintptr(i)*4)
-		var idx uint = uint(*(*C.uint)(unsafe.Pointer(ptr)))
-		mapping = append(mapping, idx)
+	for _, idx := range unsafe.Slice(p, N) {
+		mapping = append(mapping, uint(idx))
 	}
 	return mapping
 }
+
+// Compute a set of generators for the automorphism group of the graph. Every
+// automorphism of the graph can be built by composing the generators. Read
+// each generator as:
+//
+//     aut[v] -> image-of-v
+//
+// The identity is never returned so a graph with no symmetries yields an
+// empty slice.
+func (g *Digraph) Automorphisms() (generators [][]uint) {
+	return graphAutomorphisms((*C.struct_bliss_graph_struct)(g))
+}
+
+func graphAutomorphisms(G *C.struct_bliss_graph_struct) (generators [][]uint) {
+	var nofGens C.uint
+	gens := C.bliss_collect_automorphisms(G, &nofGens)
+	return copyGenerators(gens, int(nofGens), int(C.bliss_get_nof_vertices(G)))
+}
+
+func graphEdges(G *C.struct_bliss_graph_struct) (n int, edges []BlissEdge) {
+	var nofEdges C.uint
+	cedges := C.bliss_get_edges(G, &nofEdges)
+	n = int(C.bliss_get_nof_vertices(G))
+	if cedges == nil {
+		return n, nil
+	}
+	defer C.free(unsafe.Pointer(cedges))
+	edges = make([]BlissEdge, nofEdges)
+	copy(edges, unsafe.Slice((*BlissEdge)(unsafe.Pointer(cedges)), int(nofEdges)))
+	return n, edges
+}
+
+// The number of vertices and the edges (without duplicates) of the graph.
+func (g *Digraph) edgeList() (n int, edges []BlissEdge) {
+	return graphEdges((*C.struct_bliss_graph_struct)(g))
+}
+
+// Computes the orbit partition of the vertices. Read the returned slice as:
+//
+//     orbits[v] -> smallest vertex in the same orbit as v
+//
+func (g *Digraph) Orbits() (orbits []int) {
+	G := (*C.struct_bliss_graph_struct)(g)
+	return uintOrbitPartition(int(C.bliss_get_nof_vertices(G)), graphAutomorphisms(G))
+}
@@ -1,6 +1,10 @@
+//go:build cgo && !purego
+
 #include <stdlib.h>
 #include <stdio.h>
 #include <assert.h>
+#include <string.h>
+#include <vector>
 #include "graph.hh"
 extern "C" {
 #include "bliss_C.h"
@@ -27,40 +31,51 @@ extern "C" {
 */
 
 extern "C"
-struct bliss_graph_struct {
-	bliss::Digraph* g;
+struct bliss_edge_struct {
+	unsigned int Src;
+	unsigned int Targ;
 };
 
 extern "C"
-struct bliss_stats_struct {
-	/**
-	 * An approximation (due to possible rounding errors) of
-	 * the size of the automorphism group.
-	 */
-	long double group_size_approx;
-	/** The number of nodes in the search tree. */
-	long unsigned int nof_nodes;
-	/** The number of leaf nodes in the search tree. */
-	long unsigned int nof_leaf_nodes;
-	/** The number of bad nodes in the search tree. */
-	long unsigned int nof_bad_nodes;
-	/** The number of canonical representative updates. */
-	long unsigned int nof_canupdates;
-	/** The number of generator permutations. */
-	long unsigned int nof_generators;
-	/** The maximal depth of the search tree. */
-	unsigned long int max_level;
+struct bliss_graph_struct {
+	bliss::AbstractGraph* g;
+	int directed;
 };
 
-extern "C"
-struct bliss_edge_struct {
-	unsigned int Src;
-	unsigned int Targ;
-};
+static bliss::Digraph *
+bliss_digraph(BlissGraph *graph) {
+	return static_cast<bliss::Digraph*>(graph->g);
+}
+
+static bliss::Graph *
+bliss_undirected(BlissGraph *graph) {
+	return static_cast<bliss::Graph*>(graph->g);
+}
+
+static BlissGraph *
+bliss_construct(unsigned int nodes[], int len_nodes, BlissEdge edges[], int len_edges, int directed, BlissOptions *opts) {
+	BlissGraph *G;
+	int i;
+	if (directed) {
+		G = bliss_new(0);
+	} else {
+		G = bliss_new_undirected(0);
+	}
+	if (opts != NULL) {
+		bliss_set_options(G, opts);
+	}
+	for (i = 0; i < len_nodes; i++) {
+		bliss_add_vertex(G, nodes[i]);
+	}
+	for (i = 0; i < len_edges; i++) {
+		bliss_add_edge(G, edges[i].Src, edges[i].Targ);
+	}
+	return G;
+}
 
 extern "C"
 int
-bliss_construct_and_canonize(unsigned int nodes[], int len_nodes, BlissEdge edges[], int len_edges, unsigned int perm[]) {
+bliss_construct_and_canonize(unsigned int nodes[], int len_nodes, BlissEdge edges[], int len_edges, int directed, BlissOptions *opts, const int *abort, unsigned int perm[], BlissStats *stats) {
 	BlissGraph *G;
 	int i;
 	const unsigned int * p;
@@ -73,15 +88,15 @@ bliss_construct_and_canonize(unsigned int nodes[], int len_nodes, BlissEdge edge
 	if (len_edges != 0 && edges == NULL) {
 		return 3;
 	}
-	G = bliss_new(0);
-	for (i = 0; i < len_nodes; i++) {
-		bliss_add_vertex(G, nodes[i]);
-	}
-	for (i = 0; i < len_edges; i++) {
-		bliss_add_edge(G, edges[i].Src, edges[i].Targ);
+	G = bliss_construct(nodes, len_nodes, edges, len_edges, directed, opts);
+	G->g->set_search_limits(abort, opts != NULL ? opts->max_nodes : 0);
+	p = bliss_find_canonical_labeling(G, NULL, NULL, stats);
+	if (G->g->was_search_aborted()) {
+		bliss_release(G);
+		return 5;
 	}
-	p = bliss_find_canonical_labeling(G, NULL, NULL, NULL);
 	if (p == NULL) {
+		bliss_release(G);
 		return 4;
 	}
 	for (i = 0; i < len_nodes; i++) {
@@ -91,6 +106,98 @@ bliss_construct_and_canonize(unsigned int nodes[], int len_nodes, BlissEdge edge
 	return 0;
 }
 
+extern "C"
+int
+bliss_construct_and_canonize_batch(unsigned int nodes[], BlissEdge edges[], int lens[], int nof_graphs, BlissOptions *opts, unsigned int perm[], int status[]) {
+	int i;
+	int len_nodes, len_edges, directed;
+	if (nof_graphs < 0) {
+		return 1;
+	}
+	if (nodes == NULL || lens == NULL || perm == NULL || status == NULL) {
+		return 2;
+	}
+	for (i = 0; i < nof_graphs; i++) {
+		len_nodes = lens[3*i];
+		len_edges = lens[3*i+1];
+		directed = lens[3*i+2];
+		status[i] = bliss_construct_and_canonize(nodes, len_nodes, edges, len_edges, directed, opts, NULL, perm, NULL);
+		if (len_nodes > 0) {
+			nodes += len_nodes;
+			perm += len_nodes;
+		}
+		if (len_edges > 0) {
+			edges += len_edges;
+		}
+	}
+	return 0;
+}
+
+struct bliss_generators {
+	std::vector<unsigned int> perms;
+	unsigned int count;
+	bliss_generators() : count(0) {}
+};
+
+static void
+bliss_collect_generator(void *user_param, unsigned int n, const unsigned int *aut) {
+	bliss_generators *gens = (bliss_generators *)user_param;
+	gens->perms.insert(gens->perms.end(), aut, aut + n);
+	gens->count++;
+}
+
+static unsigned int *
+bliss_generators_array(bliss_generators &gens) {
+	unsigned int *arr;
+	if (gens.count == 0) {
+		return 0;
+	}
+	arr = (unsigned int *)malloc(gens.perms.size() * sizeof(unsigned int));
+	assert(arr);
+	memcpy(arr, &gens.perms[0], gens.perms.size() * sizeof(unsigned int));
+	return arr;
+}
+
+extern "C"
+int
+bliss_construct_and_find_automorphisms(unsigned int nodes[], int len_nodes, BlissEdge edges[], int len_edges, int directed, BlissOptions *opts, unsigned int **gens, unsigned int *nof_gens) {
+	BlissGraph *G;
+	if (len_nodes <= 0 || len_edges < 0) {
+		return 1;
+	}
+	if (nodes == NULL || gens == NULL || nof_gens == NULL) {
+		return 2;
+	}
+	if (len_edges != 0 && edges == NULL) {
+		return 3;
+	}
+	G = bliss_construct(nodes, len_nodes, edges, len_edges, directed, opts);
+	*gens = bliss_collect_automorphisms(G, nof_gens);
+	bliss_release(G);
+	return 0;
+}
+
+static void
+bliss_copy_stats(bliss::Stats &s, BlissStats *stats) {
+	stats->group_size_approx = (double)s.get_group_size_approx();
+	stats->group_size = strdup(s.get_group_size().c_str());
+	assert(stats->group_size);
+	stats->nof_nodes = s.get_nof_nodes();
+	stats->nof_leaf_nodes = s.get_nof_leaf_nodes();
+	stats->nof_bad_nodes = s.get_nof_bad_nodes();
+	stats->nof_canupdates = s.get_nof_canupdates();
+	stats->nof_generators = s.get_nof_generators();
+	stats->max_level = s.get_max_level();
+}
+
+extern "C"
+void bliss_release_stats(BlissStats *stats)
+{
+	assert(stats);
+	free(stats->group_size);
+	stats->group_size = 0;
+}
+
 extern "C"
 BlissGraph *bliss_new(const unsigned int n)
 {
@@ -98,19 +205,72 @@ BlissGraph *bliss_new(const unsigned int n)
 	assert(graph);
 	graph->g = new bliss::Digraph(n);
 	assert(graph->g);
+	graph->directed = 1;
+	return graph;
+}
+
+extern "C"
+BlissGraph *bliss_new_undirected(const unsigned int n)
+{
+	BlissGraph *graph = new bliss_graph_struct;
+	assert(graph);
+	graph->g = new bliss::Graph(n);
+	assert(graph->g);
+	graph->directed = 0;
+	return graph;
+}
+
+extern "C"
+int bliss_is_directed(BlissGraph *graph)
+{
+	assert(graph);
+	return graph->directed;
+}
+
+extern "C"
+void bliss_set_options(BlissGraph *graph, BlissOptions *opts)
+{
+	assert(graph);
+	assert(graph->g);
+	assert(opts);
+	assert(opts->splitting_heuristic <= bliss::Digraph::shs_flm);
+	if (graph->directed) {
+		bliss_digraph(graph)->set_splitting_heuristic(
+			(bliss::Digraph::SplittingHeuristic)opts->splitting_heuristic);
+	} else {
+		bliss_undirected(graph)->set_splitting_heuristic(
+			(bliss::Graph::SplittingHeuristic)opts->splitting_heuristic);
+	}
+	graph->g->set_component_recursion(opts->component_recursion != 0);
+	graph->g->set_failure_recording(opts->failure_recording != 0);
+	graph->g->set_long_prune_activity(opts->long_prune != 0);
+}
+
+extern "C"
+BlissGraph *bliss_read_dimacs(FILE *fp, FILE *errstr)
+{
+	bliss::Digraph *g = bliss::Digraph::read_dimacs(fp, errstr);
+	if(!g) {
+		return 0;
+	}
+	BlissGraph *graph = new bliss_graph_struct;
+	assert(graph);
+	graph->g = g;
+	graph->directed = 1;
 	return graph;
 }
 
 extern "C"
-BlissGraph *bliss_read_dimacs(FILE *fp)
+BlissGraph *bliss_read_dimacs_undirected(FILE *fp, FILE *errstr)
 {
-	bliss::Digraph *g = bliss::Digraph::read_dimacs(fp);
+	bliss::Graph *g = bliss::Graph::read_dimacs(fp, errstr);
 	if(!g) {
 		return 0;
 	}
 	BlissGraph *graph = new bliss_graph_struct;
 	assert(graph);
 	graph->g = g;
+	graph->directed = 0;
 	return graph;
 }
 
@@ -122,6 +282,28 @@ void bliss_write_dimacs(BlissGraph *graph, FILE *fp)
 	graph->g->write_dimacs(fp);
 }
 
+extern "C"
+BlissEdge *bliss_get_edges(BlissGraph *graph, unsigned int *nof_edges)
+{
+	std::vector<unsigned int> ends;
+	BlissEdge *edges;
+	assert(graph);
+	assert(graph->g);
+	assert(nof_edges);
+	graph->g->get_edges(ends);
+	*nof_edges = ends.size()/2;
+	if (*nof_edges == 0) {
+		return 0;
+	}
+	edges = (BlissEdge *)malloc(sizeof(BlissEdge) * (*nof_edges));
+	assert(edges);
+	for (unsigned int i = 0; i < *nof_edges; i++) {
+		edges[i].Src = ends[2*i];
+		edges[i].Targ = ends[2*i+1];
+	}
+	return edges;
+}
+
 extern "C"
 void bliss_release(BlissGraph *graph)
 {
@@ -152,7 +334,10 @@ unsigned int bliss_add_vertex(BlissGraph *graph, unsigned int l)
 {
 	assert(graph);
 	assert(graph->g);
-	return graph->g->add_vertex(l);
+	if (graph->directed) {
+		return bliss_digraph(graph)->add_vertex(l);
+	}
+	return bliss_undirected(graph)->add_vertex(l);
 }
 
 extern "C"
@@ -160,7 +345,11 @@ void bliss_add_edge(BlissGraph *graph, unsigned int v1, unsigned int v2)
 {
 	assert(graph);
 	assert(graph->g);
-	graph->g->add_edge(v1, v2);
+	if (graph->directed) {
+		bliss_digraph(graph)->add_edge(v1, v2);
+	} else {
+		bliss_undirected(graph)->add_edge(v1, v2);
+	}
 }
 
 extern "C"
@@ -170,7 +359,11 @@ int bliss_cmp(BlissGraph *graph1, BlissGraph *graph2)
 	assert(graph1->g);
 	assert(graph2);
 	assert(graph2->g);
-	return (*graph1->g).cmp(*graph2->g);
+	assert(graph1->directed == graph2->directed);
+	if (graph1->directed) {
+		return bliss_digraph(graph1)->cmp(*bliss_digraph(graph2));
+	}
+	return bliss_undirected(graph1)->cmp(*bliss_undirected(graph2));
 }
 
 extern "C"
@@ -190,6 +383,7 @@ BlissGraph *bliss_permute(BlissGraph *graph, const unsigned int *perm)
 	BlissGraph *permuted_graph = new bliss_graph_struct;
 	assert(permuted_graph);
 	permuted_graph->g = graph->g->permute(perm);
+	permuted_graph->directed = graph->directed;
 	return permuted_graph;
 }
 
@@ -209,17 +403,25 @@ bliss_find_automorphisms(BlissGraph *graph,
 
 	if(stats)
 	{
-		stats->group_size_approx = s.get_group_size_approx();
-		stats->nof_nodes = s.get_nof_nodes();
-		stats->nof_leaf_nodes = s.get_nof_leaf_nodes();
-		stats->nof_bad_nodes = s.get_nof_bad_nodes();
-		stats->nof_canupdates = s.get_nof_canupdates();
-		stats->nof_generators = s.get_nof_generators();
-		stats->max_level = s.get_max_level();
+		bliss_copy_stats(s, stats);
 	}
 }
 
 
+extern "C"
+unsigned int *
+bliss_collect_automorphisms(BlissGraph *graph, unsigned int *nof_gens)
+{
+	bliss_generators gens;
+	assert(graph);
+	assert(graph->g);
+	assert(nof_gens);
+	bliss_find_automorphisms(graph, bliss_collect_generator, &gens, NULL);
+	*nof_gens = gens.count;
+	return bliss_generators_array(gens);
+}
+
+
 extern "C"
 const unsigned int *
 bliss_find_canonical_labeling(BlissGraph *graph,
@@ -238,13 +440,7 @@ bliss_find_canonical_labeling(BlissGraph *graph,
 
 	if(stats)
 	{
-		stats->group_size_approx = s.get_group_size_approx();
-		stats->nof_nodes = s.get_nof_nodes();
-		stats->nof_leaf_nodes = s.get_nof_leaf_nodes();
-		stats->nof_bad_nodes = s.get_nof_bad_nodes();
-		stats->nof_canupdates = s.get_nof_canupdates();
-		stats->nof_generators = s.get_nof_generators();
-		stats->max_level = s.get_max_level();
+		bliss_copy_stats(s, stats);
 	}
 
 	return canonical_labeling;
@@ -46,21 +46,120 @@ typedef struct bliss_graph_struct BlissGraph;
  */
 typedef struct bliss_stats_struct BlissStats;
 
+struct bliss_stats_struct {
+	/**
+	 * An approximation (due to possible rounding errors) of
+	 * the size of the automorphism group.
+	 */
+	double group_size_approx;
+	/**
+	 * The exact size of the automorphism group as a nul terminated string
+	 * of decimal digits. It is malloc'ed by the search and must be released
+	 * with bliss_release_stats().
+	 */
+	char *group_size;
+	/** The number of nodes in the search tree. */
+	unsigned long nof_nodes;
+	/** The number of leaf nodes in the search tree. */
+	unsigned long nof_leaf_nodes;
+	/** The number of bad nodes in the search tree. */
+	unsigned long nof_bad_nodes;
+	/** The number of canonical representative updates. */
+	unsigned long nof_canupdates;
+	/** The number of generator permutations. */
+	unsigned long nof_generators;
+	/** The maximal depth of the search tree. */
+	unsigned long max_level;
+};
+
+/**
+ * Release the memory held by the statistics (but not the struct itself).
+ */
+void bliss_release_stats(BlissStats *stats);
+
 /**
  * represents a bliss edge
  */
 typedef struct bliss_edge_struct BlissEdge;
 
+/**
+ * \brief The search options of bliss.
+ */
+typedef struct bliss_options_struct BlissOptions;
+
+struct bliss_options_struct {
+	/**
+	 * The splitting heuristic. The value is the index of the heuristic
+	 * in the bliss::Digraph::SplittingHeuristic enumeration:
+	 * 0 = f, 1 = fs, 2 = fl, 3 = fm, 4 = fsm, 5 = flm.
+	 */
+	unsigned int splitting_heuristic;
+	/** Non-zero to use component recursion. */
+	int component_recursion;
+	/** Non-zero to use failure recording. */
+	int failure_recording;
+	/** Non-zero to use long prune. */
+	int long_prune;
+	/**
+	 * If non-zero the search is abandoned after visiting this many
+	 * nodes of the search tree. Only used by bliss_construct_and_canonize.
+	 */
+	unsigned long max_nodes;
+};
+
 /**
  * Constructs and computes the canonization of the graph given by the nodes
  * and edges.
  * nodes an array of node colors, len_nodes gives length
  * edges an array of edges (src, targ) pairs, len_edges give length
+ * directed if non-zero the edges are arcs src -> targ otherwise they are
+ *     undirected.
+ * opts is optional, if non-null the search options are set from it
+ *     (see bliss_set_options).
+ * abort is optional, if non-null the search is abandoned as soon as *abort
+ *     becomes non-zero. It may be set from another thread.
  * perm is an output param, an array of new (unsigned int) positions. aka
  *     the new permutation of the nodes.
+ * stats is an optional output param, if non-null the search statistics are
+ *     copied there.
+ * returns 0 if successful. 5 if the search was abandoned because of abort or
+ *     opts->max_nodes (the stats are still filled in). Another integer
+ *     indicates an error.
+ */
+int bliss_construct_and_canonize(unsigned int *nodes, int len_nodes, BlissEdge *edges, int len_edges, int directed, BlissOptions *opts, const int *abort, unsigned int * perm, BlissStats *stats);
+
+/**
+ * Constructs and computes the canonization of many graphs in one call. The
+ * graphs are packed one after the other into the nodes and edges arrays.
+ * Each graph is still constructed and searched on its own, the batch only
+ * saves the crossings into C.
+ * lens holds a triple (len_nodes, len_edges, directed) for each graph,
+ *     giving the number of nodes and edges of the graph and whether it is
+ *     directed (see bliss_construct_and_canonize).
+ * nof_graphs is the number of graphs (the length of lens is 3*nof_graphs).
+ * The edges of each graph refer to the nodes of that graph only, that is
+ *     they are numbered from 0 for every graph.
+ * opts is optional, it is used for every graph.
+ * perm is an output param, it is packed exactly like the nodes array.
+ * status is an output param of nof_graphs ints. status[i] is the result of
+ *     bliss_construct_and_canonize for graph i. A graph which fails does not
+ *     stop the others, its part of perm is left untouched.
+ * returns 0 if the arguments were valid (check status for each graph).
+ *     Another integer indicates an error.
+ */
+int bliss_construct_and_canonize_batch(unsigned int *nodes, BlissEdge *edges, int *lens, int nof_graphs, BlissOptions *opts, unsigned int *perm, int *status);
+/**
+ * Constructs the graph given by the nodes and edges and finds a set of
+ * generators for its automorphism group.
+ * nodes, len_nodes, edges, len_edges, directed and opts are as in
+ *     bliss_construct_and_canonize.
+ * gens is an output param, it is set to a newly malloc'ed array holding
+ *     nof_gens * len_nodes unsigned ints. Generator i is stored in
+ *     gens[i*len_nodes .. (i+1)*len_nodes-1]. The caller must free it.
+ * nof_gens is an output param, the number of generators found.
  * returns 0 if successful. Another integer indicates an error.
  */
-int bliss_construct_and_canonize(unsigned int *nodes, int len_nodes, BlissEdge *edges, int len_edges, unsigned int * perm);
+int bliss_construct_and_find_automorphisms(unsigned int *nodes, int len_nodes, BlissEdge *edges, int len_edges, int directed, BlissOptions *opts, unsigned int **gens, unsigned int *nof_gens);
 
 
 /**
@@ -72,14 +171,42 @@ BlissGraph *bliss_new(const unsigned int N);
 
 
 /**
- * Read an undirected graph from a file in the DIMACS format into a new bliss
+ * The same as bliss_new() except the graph is undirected.
+ */
+BlissGraph *bliss_new_undirected(const unsigned int N);
+
+
+/**
+ * Returns non-zero if the graph is directed.
+ */
+int bliss_is_directed(BlissGraph *graph);
+
+
+/**
+ * Set the search options (splitting heuristic, component recursion, failure
+ * recording and long prune) used by the subsequent searches on the graph.
+ * Note the options affect the computed canonical labelings. Graphs must be
+ * canonized with the same options for their canonical forms to be comparable.
+ */
+void bliss_set_options(BlissGraph *graph, BlissOptions *opts);
+
+
+/**
+ * Read a directed graph from a file in the DIMACS format into a new bliss
  * instance.
- * Returns 0 if an error occurred.
+ * Returns 0 if an error occurred. If errstr is non-null a description of the
+ * error is written there.
  * Note that in the DIMACS file the vertices are numbered from 1 to N while
  * in the bliss C API they are from 0 to N-1.
  * Thus the vertex n in the file corresponds to the vertex n-1 in the API.
  */
-BlissGraph *bliss_read_dimacs(FILE *fp);
+BlissGraph *bliss_read_dimacs(FILE *fp, FILE *errstr);
+
+
+/**
+ * The same as bliss_read_dimacs() except the graph read is undirected.
+ */
+BlissGraph *bliss_read_dimacs_undirected(FILE *fp, FILE *errstr);
 
 
 /**
@@ -91,6 +218,15 @@ BlissGraph *bliss_read_dimacs(FILE *fp);
 void bliss_write_dimacs(BlissGraph *graph, FILE *fp);
 
 
+/**
+ * The edges of the graph in a newly malloc'ed array of nof_edges edges.
+ * Duplicate edges are removed and an undirected edge is given once with the
+ * smaller vertex as its Src. The caller must free the array.
+ * Returns 0 if the graph has no edges.
+ */
+BlissEdge *bliss_get_edges(BlissGraph *graph, unsigned int *nof_edges);
+
+
 /**
  * Release the graph.
  * Note that the memory pointed by the arguments of hook functions for
@@ -121,7 +257,8 @@ unsigned int bliss_add_vertex(BlissGraph *graph, unsigned int c);
 
 
 /**
- * Add a new undirected edge in the graph.
+ * Add a new edge in the graph. If the graph is directed the edge is the arc
+ * \a v1 -> \a v2.
  * \a v1 and \a v2 are vertex indices returned by bliss_add_vertex().
  * If duplicate edges are added, they will be ignored (however, they are not
  * necessarily physically ignored immediately but may consume memory for
@@ -134,6 +271,7 @@ void bliss_add_edge(BlissGraph *graph, unsigned int v1, unsigned int v2);
  * Compare two graphs according to a total order.
  * Return -1, 0, or 1 if the first graph was smaller than, equal to,
  * or greater than, resp., the other graph.
+ * Both graphs must be directed or both must be undirected.
  * If 0 is returned, then the graphs have the same number vertices,
  * the vertices in them are colored in the same way, and they contain
  * the same edges; that is, the graphs are equal.
@@ -181,6 +319,17 @@ bliss_find_automorphisms(BlissGraph *graph,
 			 BlissStats *stats);
 
 
+/**
+ * Otherwise the same as bliss_find_automorphisms() except that instead of
+ * calling a hook the generators are collected and returned in a newly
+ * malloc'ed array of nof_gens * N unsigned ints (N as given by
+ * bliss_get_nof_vertices()). The caller must free the array.
+ * Returns 0 if no generators were found.
+ */
+unsigned int *
+bliss_collect_automorphisms(BlissGraph *graph, unsigned int *nof_gens);
+
+
 /**
  * Otherwise the same as bliss_find_automorphisms() except that
  * a canonical labeling for the graph (a bijection on {0,...,N-1}) is returned.
@@ -19,19 +19,15 @@ package bliss
   along with .  If not, see <http://www.gnu.org/licenses/>.
 */
 
-import "testing"
-
-import "reflect"
-
-func TestCanonize(t *testing.T) {
-	expected := []uint{5, 4, 1, 2, 3, 0}
-	nodes := []uint32{1, 1, 0, 0, 0, 0}
-	edges := []BlissEdge{{0, 2}, {0, 3}, {1, 4}, {1, 5}, {3, 5}, {4, 2}}
-	actual := Canonize(nodes, edges)
-	if !reflect.DeepEqual(expected, actual) {
-		t.Errorf("Expected %v got %v", expected, actual)
-	}
-}
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
 
 func TestNew(t *testing.T) {
 	Do(0, func(g *Digraph) {
@@ -46,24 +42,6 @@ func TestNew(t *testing.T) {
 	})
 }
 
-func TestPermutation(t *testing.T) {
-	Do(0, func(g *Digraph) {
-		a := g.AddVertex(1)
-		b := g.AddVertex(1)
-		c := g.AddVertex(2)
-		d := g.AddVertex(2)
-		g.AddEdge(a, b)
-		g.AddEdge(c, d)
-		g.AddEdge(a, c)
-		g.AddEdge(b, d)
-		p := g.CanonicalPermutation()
-		e := []uint{1, 0, 3, 2}
-		if !reflect.DeepEqual(p, e) {
-			t.Errorf("Expected %v got %v", e, p)
-		}
-	})
-}
-
 func TestCompare(t *testing.T) {
 	Do(0, func(g1 *Digraph) {
 		a := g1.AddVertex(1)
@@ -108,3 +86,511 @@ func TestCompare(t *testing.T) {
 		})
 	})
 }
+
+func TestAutomorphisms(t *testing.T) {
+	Do(0, func(g *Digraph) {
+		a := g.AddVertex(1)
+		b := g.AddVertex(1)
+		c := g.AddVertex(1)
+		d := g.AddVertex(2)
+		g.AddEdge(a, b)
+		g.AddEdge(b, c)
+		g.AddEdge(c, a)
+		g.AddEdge(a, d)
+		g.AddEdge(b, d)
+		g.AddEdge(c, d)
+		gens := g.Automorphisms()
+		if len(gens) != 1 {
+			t.Fatalf("expected 1 generator got %v", gens)
+		}
+		aut := gens[0]
+		if aut[d] != d {
+			t.Errorf("d should be fixed %v", aut)
+		}
+		if aut[a] == a || aut[b] == b || aut[c] == c {
+			t.Errorf("expected a rotation of the cycle %v", aut)
+		}
+	})
+	Do(0, func(g *Digraph) {
+		a := g.AddVertex(1)
+		b := g.AddVertex(2)
+		g.AddEdge(a, b)
+		if gens := g.Automorphisms(); len(gens) != 0 {
+			t.Errorf("expected no generators got %v", gens)
+		}
+	})
+}
+
+type testEdge struct{ src, targ, color int }
+
+func testMap(colors []int, edges []testEdge) *Map {
+	vi, ei := testIter(colors, edges)
+	return NewMap(len(colors), len(edges), vi, ei)
+}
+
+func testIter(colors []int, edges []testEdge) (VertexIterator, EdgeIterator) {
+	i := 0
+	var vi VertexIterator
+	vi = func() (int, VertexIterator) {
+		if i >= len(colors) {
+			return 0, nil
+		}
+		i++
+		return colors[i-1], vi
+	}
+	j := 0
+	var ei EdgeIterator
+	ei = func() (int, int, int, EdgeIterator) {
+		if j >= len(edges) {
+			return 0, 0, 0, nil
+		}
+		j++
+		e := edges[j-1]
+		return e.src, e.targ, e.color, ei
+	}
+	return vi, ei
+}
+
+func TestMapAutomorphisms(t *testing.T) {
+	// a two cycle where the vertices and edges share a color. The generators
+	// must swap the two vertices and the two edges but never mix them.
+	edges := []testEdge{{0, 1, 0}, {1, 0, 0}}
+	m := testMap([]int{0, 0}, edges)
+	Vauts, Eauts := m.Automorphisms()
+	if len(Vauts) != 1 || len(Eauts) != 1 {
+		t.Fatalf("expected 1 generator got %v %v", Vauts, Eauts)
+	}
+	if !reflect.DeepEqual(Vauts[0], []int{1, 0}) {
+		t.Errorf("unexpected vertex automorphism %v", Vauts[0])
+	}
+	if !reflect.DeepEqual(Eauts[0], []int{1, 0}) {
+		t.Errorf("unexpected edge automorphism %v", Eauts[0])
+	}
+}
+
+func TestMapAutomorphismsLargeColors(t *testing.T) {
+	// a two cycle whose vertices have the colors 0 and 1<<31. Doubling the
+	// colors to separate vertices from edges would wrap both around to 0 and
+	// let the vertices swap.
+	edges := []testEdge{{0, 1, 0}, {1, 0, 0}}
+	m := testMap([]int{0, 0}, edges)
+	m.Nodes[1] = 1 << 31
+	nodes := m.separatedNodes()
+	if nodes[0] == nodes[1] {
+		t.Fatalf("distinct vertex colors collide %v", nodes)
+	}
+	if Vauts, Eauts := m.Automorphisms(); len(Vauts) != 0 || len(Eauts) != 0 {
+		t.Errorf("expected no generators got %v %v", Vauts, Eauts)
+	}
+}
+
+func TestCanonizeStats(t *testing.T) {
+	// 25 isolated vertices, the group is the symmetric group whose order
+	// 25! does not fit in 64 bits.
+	nodes := make([]uint32, 25)
+	_, stats := CanonizeStats(nodes, nil)
+	if stats.GroupSize.String() != "15511210043330985984000000" {
+		t.Errorf("expected |Aut| = 25! got %v", stats.GroupSize)
+	}
+	if stats.Nodes == 0 || stats.LeafNodes == 0 {
+		t.Errorf("expected the search to visit some nodes %v", stats)
+	}
+	Do(0, func(g *Digraph) {
+		a := g.AddVertex(1)
+		b := g.AddVertex(1)
+		c := g.AddVertex(1)
+		g.AddEdge(a, b)
+		g.AddEdge(b, c)
+		g.AddEdge(c, a)
+		p, stats := g.CanonicalPermutationStats()
+		if !reflect.DeepEqual(p, g.CanonicalPermutation()) {
+			t.Errorf("stats changed the permutation %v", p)
+		}
+		if stats.GroupSize.Int64() != 3 {
+			t.Errorf("expected |Aut| = 3 got %v", stats.GroupSize)
+		}
+	})
+}
+
+func TestUndirected(t *testing.T) {
+	DoGraph(0, func(g1 *Graph) {
+		a := g1.AddVertex(1)
+		b := g1.AddVertex(2)
+		c := g1.AddVertex(1)
+		g1.AddEdge(a, b)
+		g1.AddEdge(b, c)
+		DoGraph(0, func(g2 *Graph) {
+			a := g2.AddVertex(2)
+			b := g2.AddVertex(1)
+			c := g2.AddVertex(1)
+			g2.AddEdge(b, a)
+			g2.AddEdge(c, a)
+			if !g1.Iso(g2) {
+				t.Error("should have been isomorphic")
+			}
+		})
+		gens := g1.Automorphisms()
+		if len(gens) != 1 || !reflect.DeepEqual(gens[0], []uint{2, 1, 0}) {
+			t.Errorf("expected the ends of the path to swap %v", gens)
+		}
+	})
+}
+
+func TestUndirectedMap(t *testing.T) {
+	// the same undirected path given with edges in opposite directions
+	vi1, ei1 := testIter([]int{0, 1, 0}, []testEdge{{0, 1, 5}, {1, 2, 5}})
+	vi2, ei2 := testIter([]int{0, 1, 0}, []testEdge{{1, 0, 5}, {2, 1, 5}})
+	m1 := NewUndirectedMap(3, 2, vi1, ei1)
+	m2 := NewUndirectedMap(3, 2, vi2, ei2)
+	_, _, _, s1 := m1.CanonicalPermutationStats()
+	_, _, _, s2 := m2.CanonicalPermutationStats()
+	if s1.GroupSize.Int64() != 2 || s2.GroupSize.Int64() != 2 {
+		t.Errorf("expected |Aut| = 2 got %v %v", s1.GroupSize, s2.GroupSize)
+	}
+	g1 := m1.Graph()
+	defer g1.Release()
+	g2 := m2.Graph()
+	defer g2.Release()
+	if !g1.Iso(g2) {
+		t.Error("should have been isomorphic")
+	}
+}
+
+// permutes the graph by the mapping into a comparable form
+func permuted(nodes []uint32, edges []BlissEdge, mapping []uint) ([]uint32, map[BlissEdge]bool) {
+	pnodes := make([]uint32, len(nodes))
+	pedges := make(map[BlissEdge]bool, len(edges))
+	for i, color := range nodes {
+		pnodes[mapping[i]] = color
+	}
+	for _, e := range edges {
+		pedges[BlissEdge{uint32(mapping[e.Src]), uint32(mapping[e.Targ])}] = true
+	}
+	return pnodes, pedges
+}
+
+func TestCanonizeOpts(t *testing.T) {
+	nodes1 := []uint32{1, 1, 0, 0, 0, 0}
+	edges1 := []BlissEdge{{0, 2}, {0, 3}, {1, 4}, {1, 5}, {3, 5}, {4, 2}}
+	// the same graph with vertices 0 <-> 1 and 2 <-> 5 swapped
+	nodes2 := []uint32{1, 1, 0, 0, 0, 0}
+	edges2 := []BlissEdge{{1, 5}, {1, 3}, {0, 4}, {0, 2}, {3, 2}, {4, 5}}
+	for h := SplitF; h <= SplitFLM; h++ {
+		for _, comprec := range []bool{true, false} {
+			opts := &Options{
+				SplittingHeuristic: h,
+				ComponentRecursion: comprec,
+				FailureRecording:   !comprec,
+				LongPrune:          comprec,
+			}
+			m1, _ := CanonizeOpts(nodes1, edges1, opts)
+			m2, _ := CanonizeOpts(nodes2, edges2, opts)
+			n1, e1 := permuted(nodes1, edges1, m1)
+			n2, e2 := permuted(nodes2, edges2, m2)
+			if !reflect.DeepEqual(n1, n2) || !reflect.DeepEqual(e1, e2) {
+				t.Errorf("canonical forms differ with options %v", opts)
+			}
+		}
+	}
+	m1, _ := CanonizeOpts(nodes1, edges1, nil)
+	if !reflect.DeepEqual(m1, Canonize(nodes1, edges1)) {
+		t.Errorf("nil options should be the defaults")
+	}
+}
+
+func TestSetOptions(t *testing.T) {
+	Do(0, func(g1 *Digraph) {
+		a := g1.AddVertex(1)
+		b := g1.AddVertex(2)
+		g1.AddEdge(a, b)
+		g1.SetOptions(&Options{SplittingHeuristic: SplitF})
+		Do(0, func(g2 *Digraph) {
+			b := g2.AddVertex(2)
+			a := g2.AddVertex(1)
+			g2.AddEdge(a, b)
+			g2.SetOptions(&Options{SplittingHeuristic: SplitF})
+			if !g1.Iso(g2) {
+				t.Error("should have been isomorphic")
+			}
+		})
+	})
+}
+
+func TestCanonizeContext(t *testing.T) {
+	nodes := make([]uint32, 10)
+	edges := []BlissEdge{}
+	mapping, _, err := CanonizeContext(context.Background(), nodes, edges, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(mapping, Canonize(nodes, edges)) {
+		t.Errorf("context variant gave a different labeling %v", mapping)
+	}
+	_, _, err = CanonizeContext(context.Background(), nodes, edges, &Options{MaxNodes: 1})
+	if e, ok := err.(*AbortError); !ok || e.Reason != NodeBudget || e.Stats == nil {
+		t.Errorf("expected the node budget to run out got %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m := testMap([]int{0, 0}, []testEdge{{0, 1, 0}})
+	_, _, _, _, err = m.CanonicalPermutationContext(ctx, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the search to be canceled got %v", err)
+	}
+}
+
+func TestDIMACS(t *testing.T) {
+	Do(0, func(g *Digraph) {
+		a := g.AddVertex(1)
+		b := g.AddVertex(2)
+		c := g.AddVertex(2)
+		g.AddEdge(a, b)
+		g.AddEdge(b, c)
+		var buf bytes.Buffer
+		if err := g.WriteDIMACS(&buf); err != nil {
+			t.Fatal(err)
+		}
+		expected := "p edge 3 2\nn 1 1\nn 2 2\nn 3 2\ne 1 2\ne 2 3\n"
+		if buf.String() != expected {
+			t.Fatalf("expected %q got %q", expected, buf.String())
+		}
+		g2, err := ReadDIMACS(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer g2.Release()
+		if g.Cmp(g2) != 0 {
+			t.Error("the graph read should equal the graph written")
+		}
+		buf.Reset()
+		if err := g2.WriteDot(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), "v1 -> v2") {
+			t.Errorf("unexpected dot output %q", buf.String())
+		}
+	})
+	_, err := ReadDIMACS(strings.NewReader("p edge 2 1\ne 1 3\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected an error on line 2 got %v", err)
+	}
+}
+
+func TestGraph6(t *testing.T) {
+	format, n, edges, err := DecodeGraph6([]byte(">>graph6<<C~"))
+	if err != nil || format != Graph6 || n != 4 || len(edges) != 6 {
+		t.Errorf("expected K4 got %v %v %v %v", format, n, edges, err)
+	}
+	// the example from nauty's formats.txt
+	format, n, edges, err = DecodeGraph6([]byte(":Fa@x^"))
+	expected := []BlissEdge{{0, 1}, {0, 2}, {1, 2}, {5, 6}}
+	if err != nil || format != Sparse6 || n != 7 || !reflect.DeepEqual(edges, expected) {
+		t.Errorf("expected %v got %v %v %v %v", expected, format, n, edges, err)
+	}
+	if s := string(EncodeGraph6(Sparse6, n, edges)); s != ":Fa@x^" {
+		t.Errorf("expected :Fa@x^ got %v", s)
+	}
+	edges = []BlissEdge{{0, 1}, {1, 0}, {2, 2}, {3, 1}, {299, 4}}
+	for _, format := range []Graph6Format{Graph6, Sparse6, Digraph6} {
+		f, m, decoded, err := DecodeGraph6(EncodeGraph6(format, 300, edges))
+		if err != nil || f != format || m != 300 {
+			t.Fatalf("%v: %v %v %v", format, f, m, err)
+		}
+		counts := map[Graph6Format]int{Graph6: 3, Sparse6: 5, Digraph6: 5}
+		if len(decoded) != counts[format] {
+			t.Errorf("%v: expected %v edges got %v", format, counts[format], decoded)
+		}
+	}
+
+	// two labelings of the Petersen graph have the same canonical graph6
+	lines := ""
+	for _, g := range []testGraph{petersenGraph(), petersenGraph().shuffled(rand.New(rand.NewSource(1)))} {
+		lines += string(EncodeGraph6(Graph6, len(g.nodes), g.edges)) + "\n"
+	}
+	graphs, err := ReadGraph6(strings.NewReader(lines))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var canon []string
+	for _, g := range graphs {
+		var buf bytes.Buffer
+		c := g.Canonical()
+		if err := c.WriteGraph6(&buf); err != nil {
+			t.Fatal(err)
+		}
+		canon = append(canon, buf.String())
+		c.Release()
+		g.Release()
+	}
+	if canon[0] != canon[1] || lines == canon[0]+canon[1] {
+		t.Errorf("expected equal canonical forms got %q", canon)
+	}
+
+	digraphs, err := ReadDigraph6(strings.NewReader("&B?_\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := digraphs[0].WriteDigraph6(&buf); err != nil || buf.String() != "&B?_\n" {
+		t.Errorf("expected the digraph6 to round trip got %q %v", buf.String(), err)
+	}
+	digraphs[0].Release()
+	DoGraph(0, func(g *Graph) {
+		for i := 0; i < 3; i++ {
+			g.AddVertex(0)
+		}
+		g.AddEdge(1, 0)
+		g.AddEdge(0, 1)
+		g.AddEdge(2, 2)
+		expected := string(EncodeGraph6(Sparse6, 3, []BlissEdge{{0, 1}, {2, 2}})) + "\n"
+		var buf bytes.Buffer
+		if err := g.WriteSparse6(&buf); err != nil || buf.String() != expected {
+			t.Errorf("expected %q got %q %v", expected, buf.String(), err)
+		}
+	})
+	// headers announcing huge graphs in a few bytes
+	for _, line := range []string{"&~~C?????", "~~C?????", ":~~C?????", "&~~?????~", "~~?????~", ":~~?~~~~~"} {
+		if _, _, _, err := DecodeGraph6([]byte(line)); err == nil {
+			t.Errorf("expected %q to be rejected", line)
+		}
+	}
+	if _, n, _, err := DecodeGraph6([]byte(":~@??")); err != nil || n != 1<<12 {
+		t.Errorf("expected a sparse6 graph with %v vertices got %v %v", 1<<12, n, err)
+	}
+	_, err = ReadGraph6(strings.NewReader("C~\nC~~\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected an error on line 2 got %v", err)
+	}
+}
+
+func TestOrbits(t *testing.T) {
+	Do(0, func(g *Digraph) {
+		a := g.AddVertex(1)
+		b := g.AddVertex(2)
+		c := g.AddVertex(2)
+		d := g.AddVertex(2)
+		g.AddEdge(a, b)
+		g.AddEdge(a, c)
+		g.AddEdge(d, a)
+		orbits := g.Orbits()
+		if !reflect.DeepEqual(orbits, []int{0, 1, 1, 3}) {
+			t.Errorf("unexpected orbits %v", orbits)
+		}
+	})
+	m := testMap([]int{0, 0, 0}, []testEdge{{0, 1, 0}, {1, 2, 0}, {2, 0, 0}})
+	Vorbits, Eorbits := m.Orbits()
+	if !reflect.DeepEqual(Vorbits, []int{0, 0, 0}) || !reflect.DeepEqual(Eorbits, []int{0, 0, 0}) {
+		t.Errorf("unexpected orbits %v %v", Vorbits, Eorbits)
+	}
+}
+
+func testBatch() []BatchGraph {
+	return []BatchGraph{
+		{
+			Nodes: []uint32{1, 1, 0, 0, 0, 0},
+			Edges: []BlissEdge{{0, 2}, {0, 3}, {1, 4}, {1, 5}, {3, 5}, {4, 2}},
+		},
+		{
+			Nodes: []uint32{0},
+		},
+		{
+			Nodes:      []uint32{0, 1, 0},
+			Edges:      []BlissEdge{{1, 0}, {1, 2}},
+			Undirected: true,
+		},
+	}
+}
+
+func TestCanonizeBatch(t *testing.T) {
+	graphs := testBatch()
+	mappings, errs := CanonizeBatch(graphs)
+	if errs != nil {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	if len(mappings) != len(graphs) {
+		t.Fatalf("expected %v mappings got %v", len(graphs), len(mappings))
+	}
+	for i, g := range graphs {
+		var expected []uint
+		if g.Undirected {
+			expected = CanonizeUndirected(g.Nodes, g.Edges)
+		} else {
+			expected = Canonize(g.Nodes, g.Edges)
+		}
+		if !reflect.DeepEqual(expected, mappings[i]) {
+			t.Errorf("graph %v: expected %v got %v", i, expected, mappings[i])
+		}
+	}
+	if mappings, errs := CanonizeBatch(nil); mappings != nil || errs != nil {
+		t.Error("expected no mappings for an empty batch")
+	}
+}
+
+func TestCanonizeBatchEmptyGraph(t *testing.T) {
+	graphs := testBatch()
+	graphs = append(graphs[:1], append([]BatchGraph{{}}, graphs[1:]...)...)
+	mappings, errs := CanonizeBatch(graphs)
+	if len(mappings) != len(graphs) || len(errs) != len(graphs) {
+		t.Fatalf("expected %v mappings and errors got %v %v", len(graphs), len(mappings), len(errs))
+	}
+	if errs[1] == nil || mappings[1] != nil {
+		t.Errorf("expected the empty graph to fail got %v %v", mappings[1], errs[1])
+	}
+	for i, g := range graphs {
+		if i == 1 {
+			continue
+		}
+		if errs[i] != nil {
+			t.Fatalf("graph %v: unexpected error %v", i, errs[i])
+		}
+		var expected []uint
+		if g.Undirected {
+			expected = CanonizeUndirected(g.Nodes, g.Edges)
+		} else {
+			expected = Canonize(g.Nodes, g.Edges)
+		}
+		if !reflect.DeepEqual(expected, mappings[i]) {
+			t.Errorf("graph %v: expected %v got %v", i, expected, mappings[i])
+		}
+	}
+}
+
+func TestCanonicalPermutationBatch(t *testing.T) {
+	maps := []*Map{
+		testMap([]int{0, 0, 1}, []testEdge{{0, 1, 2}, {1, 2, 3}}),
+		testMap([]int{1, 0}, []testEdge{{1, 0, 2}}),
+	}
+	Vords, Eords, canonized := CanonicalPermutationBatch(maps)
+	for i, m := range maps {
+		Vord, Eord, c := m.CanonicalPermutation()
+		if !reflect.DeepEqual(Vord, Vords[i]) || !reflect.DeepEqual(Eord, Eords[i]) || c != canonized[i] {
+			t.Errorf("map %v: expected %v %v %v got %v %v %v", i, Vord, Eord, c, Vords[i], Eords[i], canonized[i])
+		}
+	}
+}
+
+func benchBatch(n int) []BatchGraph {
+	graphs := make([]BatchGraph, 0, n)
+	for i := 0; i < n; i++ {
+		graphs = append(graphs, testBatch()[i%3])
+	}
+	return graphs
+}
+
+func BenchmarkCanonize(b *testing.B) {
+	graphs := benchBatch(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, g := range graphs {
+			canonize(g.Nodes, g.Edges, !g.Undirected, nil, false)
+		}
+	}
+}
+
+func BenchmarkCanonizeBatch(b *testing.B) {
+	graphs := benchBatch(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		CanonizeBatch(graphs)
+	}
+}
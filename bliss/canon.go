@@ -0,0 +1,564 @@
+package bliss
+
+/*
+  Copyright (c) 2016 Tim Henderson
+  Released under the GNU General Public License version 3.
+
+  This file is part of goiso a wrapper around bliss.
+
+  bliss is free software: you can redistribute it and/or modify
+  it under the terms of the GNU General Public License version 3
+  as published by the Free Software Foundation.
+
+  bliss is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU General Public License for more details.
+
+  You should have received a copy of the GNU General Public License
+  along with .  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"sync/atomic"
+)
+
+// This file is a pure Go canonical labeling search. It is the backend used
+// when the package is built without cgo (or with the purego build tag). It
+// follows the same individualization-refinement scheme as bliss:
+//
+//   - the vertices are partitioned by color and the partition is refined
+//     until it is equitable (every vertex of a cell has the same number of
+//     neighbors in every cell)
+//   - a vertex of a non-singleton cell is individualized and the partition
+//     refined again until it is discrete. Each discrete partition (a leaf of
+//     the search tree) is a labeling of the graph.
+//   - the canonical labeling is the leaf with the best invariants along its
+//     path and the smallest certificate (sorted relabeled edge list)
+//   - leaves with equal certificates give automorphisms which prune the
+//     rest of the tree
+//
+// The canonical labelings differ from the ones computed by bliss but two
+// graphs get the same canonical form iff they are isomorphic.
+
+// The graph being searched. The edges are deduplicated into adjacency lists.
+// For undirected graphs out holds both directions and in is nil.
+type cgraph struct {
+	colors   []uint32
+	directed bool
+	out      [][]int
+	in       [][]int
+}
+
+func newCGraph(nodes []uint32, edges []BlissEdge, directed bool) *cgraph {
+	n := len(nodes)
+	g := &cgraph{
+		colors:   nodes,
+		directed: directed,
+		out:      make([][]int, n),
+	}
+	if directed {
+		g.in = make([][]int, n)
+	}
+	for _, e := range edges {
+		u, v := int(e.Src), int(e.Targ)
+		if u >= n || v >= n {
+			panic(fmt.Errorf("bliss: edge (%v, %v) is not in a graph with %v vertices", u, v, n))
+		}
+		g.out[u] = append(g.out[u], v)
+		if directed {
+			g.in[v] = append(g.in[v], u)
+		} else if u != v {
+			g.out[v] = append(g.out[v], u)
+		}
+	}
+	for v := range g.out {
+		g.out[v] = uniqInts(g.out[v])
+	}
+	for v := range g.in {
+		g.in[v] = uniqInts(g.in[v])
+	}
+	return g
+}
+
+// sorts and removes the duplicates from s in place
+func uniqInts(s []int) []int {
+	sort.Ints(s)
+	j := 0
+	for i, x := range s {
+		if i == 0 || x != s[j-1] {
+			s[j] = x
+			j++
+		}
+	}
+	return s[:j]
+}
+
+func cmpInts(a, b []int) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] < b[i] {
+			return -1
+		} else if a[i] > b[i] {
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
+
+// An ordered partition of the vertices. Cells occupy contiguous positions of
+// lab and are identified by their first position.
+type partition struct {
+	lab   []int // lab[position] -> vertex
+	cell  []int // cell[vertex] -> first position of the vertex's cell
+	end   []int // end[first position of a cell] -> one past its last position
+	cells int   // the number of cells
+}
+
+// The partition of the vertices by color, ordered by color.
+func (g *cgraph) colorPartition() *partition {
+	n := len(g.colors)
+	p := &partition{
+		lab:  make([]int, n),
+		cell: make([]int, n),
+		end:  make([]int, n),
+	}
+	for i := range p.lab {
+		p.lab[i] = i
+	}
+	sort.SliceStable(p.lab, func(i, j int) bool {
+		return g.colors[p.lab[i]] < g.colors[p.lab[j]]
+	})
+	for i := 0; i < n; {
+		j := i + 1
+		for j < n && g.colors[p.lab[j]] == g.colors[p.lab[i]] {
+			j++
+		}
+		for k := i; k < j; k++ {
+			p.cell[p.lab[k]] = i
+		}
+		p.end[i] = j
+		p.cells++
+		i = j
+	}
+	return p
+}
+
+func (p *partition) discrete() bool {
+	return p.cells == len(p.lab)
+}
+
+// Splits v off into a singleton cell placed in front of the rest of its
+// cell. Returns a new partition.
+func (p *partition) individualize(v int) *partition {
+	q := &partition{
+		lab:   append([]int(nil), p.lab...),
+		cell:  append([]int(nil), p.cell...),
+		end:   append([]int(nil), p.end...),
+		cells: p.cells + 1,
+	}
+	start := q.cell[v]
+	for i := start; i < q.end[start]; i++ {
+		if q.lab[i] == v {
+			q.lab[i], q.lab[start] = q.lab[start], q.lab[i]
+			break
+		}
+	}
+	q.end[start+1] = q.end[start]
+	q.end[start] = start + 1
+	for i := start + 1; i < q.end[start+1]; i++ {
+		q.cell[q.lab[i]] = start + 1
+	}
+	return q
+}
+
+// Refines p in place into the coarsest equitable partition finer than p.
+// Each cell is split by the multisets of cells its vertices have neighbors
+// in and the pieces are ordered by those multisets so the refinement does
+// not depend on the numbering of the vertices. Returns an invariant of the
+// refined partition: the size and neighborhood of every cell in order.
+func (g *cgraph) refine(p *partition) (inv []int) {
+	n := len(p.lab)
+	sigs := make([][]int, n)
+	for {
+		for v := range sigs {
+			sig := append(sigs[v][:0], p.cell[v])
+			sig = neighborCells(sig, p, g.out[v])
+			if g.directed {
+				sig = append(sig, -1)
+				sig = neighborCells(sig, p, g.in[v])
+			}
+			sigs[v] = sig
+		}
+		cells := p.cells
+		for start := 0; start < n; {
+			end := p.end[start]
+			if end-start > 1 {
+				seg := p.lab[start:end]
+				sort.SliceStable(seg, func(i, j int) bool {
+					return cmpInts(sigs[seg[i]], sigs[seg[j]]) < 0
+				})
+				first := start
+				for i := start + 1; i <= end; i++ {
+					if i < end && cmpInts(sigs[p.lab[i-1]], sigs[p.lab[i]]) == 0 {
+						continue
+					}
+					p.end[first] = i
+					for k := first; k < i; k++ {
+						p.cell[p.lab[k]] = first
+					}
+					if first != start {
+						p.cells++
+					}
+					first = i
+				}
+			}
+			start = end
+		}
+		if p.cells == cells {
+			break
+		}
+	}
+	inv = make([]int, 0, 2*p.cells)
+	for start := 0; start < n; start = p.end[start] {
+		inv = append(inv, -2, p.end[start]-start)
+		inv = append(inv, sigs[p.lab[start]]...)
+	}
+	return inv
+}
+
+// appends the sorted cells of the vertices in adj to sig
+func neighborCells(sig []int, p *partition, adj []int) []int {
+	from := len(sig)
+	for _, u := range adj {
+		sig = append(sig, p.cell[u])
+	}
+	sort.Ints(sig[from:])
+	return sig
+}
+
+// The number of non-singleton cells the cell at start is non-trivially
+// connected to: its vertices have some but not all of the cell's vertices as
+// neighbors. p must be equitable.
+func (g *cgraph) connectivity(p *partition, start int) int {
+	v := p.lab[start]
+	conn := 0
+	count := func(adj []int) {
+		counts := make(map[int]int)
+		for _, u := range adj {
+			counts[p.cell[u]]++
+		}
+		for c, k := range counts {
+			if size := p.end[c] - c; size > 1 && k < size {
+				conn++
+			}
+		}
+	}
+	count(g.out[v])
+	if g.directed {
+		count(g.in[v])
+	}
+	return conn
+}
+
+// The relabeled edge list of the graph under a discrete partition. Two
+// labelings give the same certificate iff they give the same graph.
+func (g *cgraph) certificate(p *partition) []uint64 {
+	cert := make([]uint64, 0, len(p.lab))
+	for u, adj := range g.out {
+		pu := p.cell[u]
+		for _, v := range adj {
+			pv := p.cell[v]
+			if !g.directed && pv < pu {
+				continue
+			}
+			cert = append(cert, uint64(pu)<<32|uint64(pv))
+		}
+	}
+	sort.Slice(cert, func(i, j int) bool { return cert[i] < cert[j] })
+	return cert
+}
+
+func cmpCerts(a, b []uint64) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] < b[i] {
+			return -1
+		} else if a[i] > b[i] {
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
+
+// A leaf of the search tree.
+type searchLeaf struct {
+	lab  []int    // the discrete partition
+	cert []uint64 // the certificate of the labeling
+	path []int    // the vertices individualized to reach the leaf
+	inv  [][]int  // the invariants of the nodes on the path
+}
+
+type canonSearch struct {
+	g         *cgraph
+	heuristic SplittingHeuristic
+	maxNodes  uint64
+	abort     *int32 // set to an AbortReason to stop the search, may be nil
+	aborted   AbortReason
+	stats     Stats
+	groupSize *big.Int
+	gens      [][]int
+	first     *searchLeaf
+	best      *searchLeaf
+	// the state of the current path, indexed by level
+	path    []int
+	pathInv [][]int
+	eqFirst []bool // are the invariants equal to the first path's so far
+	relBest []int  // compares the invariants so far to the best path's
+}
+
+// Searches for the canonical labeling and the automorphism group of the
+// graph. If the search is aborted (see Options.MaxNodes and the abort flag)
+// the reason is returned and mapping is nil. Read the results as:
+//
+//     mapping[original-index-for-v] -> new-index-for-v
+//     generators[i][v] -> image-of-v
+//
+func searchCanonical(nodes []uint32, edges []BlissEdge, directed bool, opts *Options, abort *int32) (mapping []uint, generators [][]uint, stats *Stats, aborted AbortReason) {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+	if err := opts.validate(); err != nil {
+		panic(err)
+	}
+	s := &canonSearch{
+		g:         newCGraph(nodes, edges, directed),
+		heuristic: opts.SplittingHeuristic,
+		maxNodes:  opts.MaxNodes,
+		abort:     abort,
+		groupSize: big.NewInt(1),
+	}
+	if len(nodes) > 0 {
+		s.search(s.g.colorPartition(), 0)
+	}
+	stats = &s.stats
+	stats.GroupSize = s.groupSize
+	stats.GroupSizeApprox, _ = new(big.Float).SetInt(s.groupSize).Float64()
+	stats.Generators = uint64(len(s.gens))
+	generators = make([][]uint, 0, len(s.gens))
+	for _, gen := range s.gens {
+		aut := make([]uint, len(gen))
+		for v, u := range gen {
+			aut[v] = uint(u)
+		}
+		generators = append(generators, aut)
+	}
+	if s.aborted != 0 || s.best == nil {
+		return nil, generators, stats, s.aborted
+	}
+	mapping = make([]uint, len(nodes))
+	for i, v := range s.best.lab {
+		mapping[v] = uint(i)
+	}
+	return mapping, generators, stats, 0
+}
+
+// Searches the subtree rooted at the node with partition p. Returns the
+// level of the node at which the search continues: normally the parent
+// (level-1) but it may jump further back once an automorphism shows the rest
+// of the subtree is redundant. -1 ends the search.
+func (s *canonSearch) search(p *partition, level int) int {
+	if s.abort != nil {
+		if r := AbortReason(atomic.LoadInt32(s.abort)); r != 0 {
+			s.aborted = r
+			return -1
+		}
+	}
+	if s.maxNodes > 0 && s.stats.Nodes >= s.maxNodes {
+		s.aborted = NodeBudget
+		return -1
+	}
+	s.stats.Nodes++
+	if uint64(level) > s.stats.MaxLevel {
+		s.stats.MaxLevel = uint64(level)
+	}
+	inv := s.g.refine(p)
+	s.pathInv = append(s.pathInv[:level], inv)
+	onFirst := s.first == nil
+	eqFirst := true
+	relBest := 0
+	if !onFirst {
+		eqFirst = (level == 0 || s.eqFirst[level-1]) &&
+			level < len(s.first.inv) && cmpInts(inv, s.first.inv[level]) == 0
+		if level > 0 {
+			relBest = s.relBest[level-1]
+		}
+		if relBest == 0 {
+			relBest = cmpInts(inv, s.best.inv[level])
+		}
+	}
+	s.eqFirst = append(s.eqFirst[:level], eqFirst)
+	s.relBest = append(s.relBest[:level], relBest)
+	if relBest < 0 && !eqFirst {
+		// can neither be the canonical labeling nor an automorphism
+		s.stats.BadNodes++
+		return level - 1
+	}
+	if p.discrete() {
+		return s.leaf(p, level)
+	}
+	start := s.target(p)
+	cands := append([]int(nil), p.lab[start:p.end[start]]...)
+	sort.Ints(cands)
+	explored := make([]int, 0, len(cands))
+	var orbits []int
+	nofGens := -1
+	for _, w := range cands {
+		if len(s.gens) != nofGens {
+			orbits = s.stabilizerOrbits(level)
+			nofGens = len(s.gens)
+		}
+		if inOrbits(orbits, explored, w) {
+			continue
+		}
+		explored = append(explored, w)
+		s.path = append(s.path[:level], w)
+		if ret := s.search(p.individualize(w), level+1); ret < level {
+			return ret
+		}
+	}
+	if onFirst {
+		// every vertex equivalent to the first path's choice has been found
+		// so by the orbit-stabilizer theorem the group size grows by the
+		// size of its orbit.
+		orbits = s.stabilizerOrbits(level)
+		size := 0
+		for _, o := range orbits {
+			if o == orbits[s.first.path[level]] {
+				size++
+			}
+		}
+		s.groupSize.Mul(s.groupSize, big.NewInt(int64(size)))
+	}
+	return level - 1
+}
+
+func (s *canonSearch) leaf(p *partition, level int) int {
+	s.stats.LeafNodes++
+	l := &searchLeaf{
+		lab:  append([]int(nil), p.lab...),
+		cert: s.g.certificate(p),
+		path: append([]int(nil), s.path[:level]...),
+		inv:  append([][]int(nil), s.pathInv[:level+1]...),
+	}
+	if s.first == nil {
+		s.first = l
+		s.best = l
+		s.stats.CanonUpdates++
+		return level - 1
+	}
+	if s.eqFirst[level] && cmpCerts(l.cert, s.first.cert) == 0 {
+		s.automorphism(s.first, l)
+		return commonPrefix(l.path, s.first.path)
+	}
+	rel := s.relBest[level]
+	if rel == 0 {
+		// a smaller certificate is better
+		rel = -cmpCerts(l.cert, s.best.cert)
+		if rel == 0 {
+			s.automorphism(s.best, l)
+			return commonPrefix(l.path, s.best.path)
+		}
+	}
+	if rel > 0 {
+		s.best = l
+		s.stats.CanonUpdates++
+		for i := range s.relBest {
+			s.relBest[i] = 0
+		}
+	}
+	return level - 1
+}
+
+// Records the automorphism mapping leaf a onto leaf b.
+func (s *canonSearch) automorphism(a, b *searchLeaf) {
+	gen := make([]int, len(a.lab))
+	for i, v := range a.lab {
+		gen[v] = b.lab[i]
+	}
+	s.gens = append(s.gens, gen)
+}
+
+// The orbits of the group generated by the automorphisms found so far which
+// fix the vertices individualized on the current path above level.
+func (s *canonSearch) stabilizerOrbits(level int) []int {
+	gens := make([][]int, 0, len(s.gens))
+	for _, gen := range s.gens {
+		fixes := true
+		for _, v := range s.path[:level] {
+			if gen[v] != v {
+				fixes = false
+				break
+			}
+		}
+		if fixes {
+			gens = append(gens, gen)
+		}
+	}
+	return OrbitPartition(len(s.g.colors), gens)
+}
+
+func inOrbits(orbits, explored []int, w int) bool {
+	for _, x := range explored {
+		if orbits[x] == orbits[w] {
+			return true
+		}
+	}
+	return false
+}
+
+func commonPrefix(a, b []int) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// Picks the first position of the cell to individualize a vertex from
+// according to the splitting heuristic. p must not be discrete.
+func (s *canonSearch) target(p *partition) int {
+	best := -1
+	bestSize, bestConn := 0, 0
+	for start := 0; start < len(p.lab); start = p.end[start] {
+		size := p.end[start] - start
+		if size == 1 {
+			continue
+		}
+		if s.heuristic == SplitF {
+			return start
+		}
+		conn := 0
+		if s.heuristic >= SplitFM {
+			conn = s.g.connectivity(p, start)
+		}
+		better := best < 0
+		switch s.heuristic {
+		case SplitFS:
+			better = better || size < bestSize
+		case SplitFL:
+			better = better || size > bestSize
+		case SplitFM:
+			better = better || conn > bestConn
+		case SplitFSM:
+			better = better || conn > bestConn || (conn == bestConn && size < bestSize)
+		case SplitFLM:
+			better = better || conn > bestConn || (conn == bestConn && size > bestSize)
+		}
+		if better {
+			best, bestSize, bestConn = start, size, conn
+		}
+	}
+	return best
+}
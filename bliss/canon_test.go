@@ -0,0 +1,214 @@
+package bliss
+
+/*
+  Copyright (c) 2016 Tim Henderson
+  Released under the GNU General Public License version 3.
+
+  This file is part of goiso a wrapper around bliss.
+
+  bliss is free software: you can redistribute it and/or modify
+  it under the terms of the GNU General Public License version 3
+  as published by the Free Software Foundation.
+
+  bliss is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU General Public License for more details.
+
+  You should have received a copy of the GNU General Public License
+  along with .  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+type testGraph struct {
+	nodes    []uint32
+	edges    []BlissEdge
+	directed bool
+}
+
+func randomGraph(r *rand.Rand, n, m, colors int, directed bool) testGraph {
+	g := testGraph{nodes: make([]uint32, n), directed: directed}
+	for i := range g.nodes {
+		g.nodes[i] = uint32(r.Intn(colors))
+	}
+	for i := 0; i < m; i++ {
+		g.edges = append(g.edges, BlissEdge{uint32(r.Intn(n)), uint32(r.Intn(n))})
+	}
+	return g
+}
+
+func cycleGraph(n int, directed bool) testGraph {
+	g := testGraph{nodes: make([]uint32, n), directed: directed}
+	for i := 0; i < n; i++ {
+		g.edges = append(g.edges, BlissEdge{uint32(i), uint32((i + 1) % n)})
+	}
+	return g
+}
+
+// the complete bipartite graph K(n,n)
+func bipartiteGraph(n int) testGraph {
+	g := testGraph{nodes: make([]uint32, 2*n)}
+	for i := 0; i < n; i++ {
+		for j := n; j < 2*n; j++ {
+			g.edges = append(g.edges, BlissEdge{uint32(i), uint32(j)})
+		}
+	}
+	return g
+}
+
+func petersenGraph() testGraph {
+	g := testGraph{nodes: make([]uint32, 10)}
+	for i := 0; i < 5; i++ {
+		g.edges = append(g.edges,
+			BlissEdge{uint32(i), uint32((i + 1) % 5)},
+			BlissEdge{uint32(i), uint32(i + 5)},
+			BlissEdge{uint32(i + 5), uint32((i+2)%5 + 5)},
+		)
+	}
+	return g
+}
+
+func (g testGraph) shuffled(r *rand.Rand) testGraph {
+	p := r.Perm(len(g.nodes))
+	s := testGraph{nodes: make([]uint32, len(g.nodes)), directed: g.directed}
+	for v, color := range g.nodes {
+		s.nodes[p[v]] = color
+	}
+	for _, e := range g.edges {
+		s.edges = append(s.edges, BlissEdge{uint32(p[e.Src]), uint32(p[e.Targ])})
+	}
+	return s
+}
+
+// The graph relabeled by the mapping as a comparable string.
+func (g testGraph) form(mapping []uint) string {
+	nodes := make([]uint32, len(g.nodes))
+	for v, color := range g.nodes {
+		nodes[mapping[v]] = color
+	}
+	edges := make(map[BlissEdge]bool, len(g.edges))
+	for _, e := range g.edges {
+		u, v := uint32(mapping[e.Src]), uint32(mapping[e.Targ])
+		if !g.directed && u > v {
+			u, v = v, u
+		}
+		edges[BlissEdge{u, v}] = true
+	}
+	list := make([]BlissEdge, 0, len(edges))
+	for e := range edges {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Src != list[j].Src {
+			return list[i].Src < list[j].Src
+		}
+		return list[i].Targ < list[j].Targ
+	})
+	return fmt.Sprint(nodes, list)
+}
+
+func (g testGraph) pureGo() (string, *Stats) {
+	mapping, _, stats, _ := searchCanonical(g.nodes, g.edges, g.directed, nil, nil)
+	return g.form(mapping), stats
+}
+
+func (g testGraph) backend() (string, *Stats) {
+	mapping, stats := canonize(g.nodes, g.edges, g.directed, nil, true)
+	return g.form(mapping), stats
+}
+
+func testGraphs(r *rand.Rand) []testGraph {
+	graphs := []testGraph{
+		cycleGraph(7, false),
+		cycleGraph(7, true),
+		cycleGraph(12, false),
+		bipartiteGraph(4),
+		petersenGraph(),
+		{nodes: make([]uint32, 9)},
+	}
+	for i := 0; i < 60; i++ {
+		n := 1 + r.Intn(12)
+		graphs = append(graphs, randomGraph(r, n, r.Intn(3*n), 1+r.Intn(3), i%2 == 0))
+	}
+	return graphs
+}
+
+func TestPureGoCanonical(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	for i, g := range testGraphs(r) {
+		form, stats := g.pureGo()
+		_, expected := g.backend()
+		if stats.GroupSize.Cmp(expected.GroupSize) != 0 {
+			t.Errorf("graph %v: expected |Aut| = %v got %v", i, expected.GroupSize, stats.GroupSize)
+		}
+		for j := 0; j < 5; j++ {
+			if f, _ := g.shuffled(r).pureGo(); f != form {
+				t.Errorf("graph %v: the canonical form changed when relabeled\n%v\n%v", i, form, f)
+			}
+		}
+	}
+}
+
+func TestPureGoAgreesWithBackend(t *testing.T) {
+	// the pure Go search must agree with the backend on isomorphism. Small
+	// graphs with few edges have many isomorphic pairs.
+	r := rand.New(rand.NewSource(11))
+	graphs := make([]testGraph, 0, 200)
+	for i := 0; i < 200; i++ {
+		graphs = append(graphs, randomGraph(r, 5, 4, 2, i%2 == 0))
+	}
+	isos := 0
+	for i := range graphs {
+		for j := i + 1; j < len(graphs); j++ {
+			a, b := graphs[i], graphs[j]
+			if a.directed != b.directed {
+				continue
+			}
+			fa, _ := a.pureGo()
+			fb, _ := b.pureGo()
+			ba, _ := a.backend()
+			bb, _ := b.backend()
+			if (fa == fb) != (ba == bb) {
+				t.Fatalf("graphs %v and %v: isomorphic by the backend %v by the pure Go search %v", i, j, ba == bb, fa == fb)
+			}
+			if fa == fb {
+				isos++
+			}
+		}
+	}
+	if isos == 0 {
+		t.Error("expected some isomorphic pairs")
+	}
+}
+
+func TestPureGoAutomorphisms(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for i, g := range testGraphs(r) {
+		_, gens, _, _ := searchCanonical(g.nodes, g.edges, g.directed, nil, nil)
+		expected := g.form(identity(len(g.nodes)))
+		for _, gen := range gens {
+			if g.form(gen) != expected {
+				t.Errorf("graph %v: %v is not an automorphism", i, gen)
+			}
+		}
+	}
+	g := cycleGraph(4, true)
+	mapping, _, _, reason := searchCanonical(g.nodes, g.edges, g.directed, &Options{MaxNodes: 1}, nil)
+	if mapping != nil || reason != NodeBudget {
+		t.Errorf("expected the node budget to run out got %v %v", mapping, reason)
+	}
+}
+
+func identity(n int) []uint {
+	p := make([]uint, n)
+	for i := range p {
+		p[i] = uint(i)
+	}
+	return p
+}
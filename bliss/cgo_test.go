@@ -0,0 +1,58 @@
+//go:build cgo && !purego
+
+package bliss
+
+/*
+  Copyright (c) 2016 Tim Henderson
+  Released under the GNU General Public License version 3.
+
+  This file is part of goiso a wrapper around bliss.
+
+  bliss is free software: you can redistribute it and/or modify
+  it under the terms of the GNU General Public License version 3
+  as published by the Free Software Foundation.
+
+  bliss is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU General Public License for more details.
+
+  You should have received a copy of the GNU General Public License
+  along with .  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+import (
+	"reflect"
+	"testing"
+)
+
+// These tests pin the exact labelings computed by bliss. The pure Go backend
+// computes different (but equally canonical) labelings.
+
+func TestCanonize(t *testing.T) {
+	expected := []uint{5, 4, 1, 2, 3, 0}
+	nodes := []uint32{1, 1, 0, 0, 0, 0}
+	edges := []BlissEdge{{0, 2}, {0, 3}, {1, 4}, {1, 5}, {3, 5}, {4, 2}}
+	actual := Canonize(nodes, edges)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %v got %v", expected, actual)
+	}
+}
+
+func TestPermutation(t *testing.T) {
+	Do(0, func(g *Digraph) {
+		a := g.AddVertex(1)
+		b := g.AddVertex(1)
+		c := g.AddVertex(2)
+		d := g.AddVertex(2)
+		g.AddEdge(a, b)
+		g.AddEdge(c, d)
+		g.AddEdge(a, c)
+		g.AddEdge(b, d)
+		p := g.CanonicalPermutation()
+		e := []uint{1, 0, 3, 2}
+		if !reflect.DeepEqual(p, e) {
+			t.Errorf("Expected %v got %v", e, p)
+		}
+	})
+}
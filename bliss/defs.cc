@@ -1,3 +1,5 @@
+//go:build cgo && !purego
+
 #include <cstdlib>
 #include <cstdio>
 #include "defs.hh"
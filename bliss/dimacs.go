@@ -0,0 +1,145 @@
+//go:build cgo && !purego
+
+package bliss
+
+/*
+  Copyright (c) 2016 Tim Henderson
+  Released under the GNU General Public License version 3.
+
+  This file is part of goiso a wrapper around bliss.
+
+  bliss is free software: you can redistribute it and/or modify
+  it under the terms of the GNU General Public License version 3
+  as published by the Free Software Foundation.
+
+  bliss is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU General Public License for more details.
+
+  You should have received a copy of the GNU General Public License
+  along with goiso.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+#include "bliss_C.h"
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unsafe"
+)
+
+// Read a directed graph in the DIMACS format used by the standalone bliss
+// tool (see http://www.tcs.hut.fi/Software/bliss/fileformat.shtml). The
+// reader does not need to be a file, any io.Reader works. The returned graph
+// must be released.
+func ReadDIMACS(r io.Reader) (*Digraph, error) {
+	G, err := readDIMACS(r, true)
+	return (*Digraph)(G), err
+}
+
+// The same as ReadDIMACS except the graph read is undirected.
+func ReadDIMACSGraph(r io.Reader) (*Graph, error) {
+	G, err := readDIMACS(r, false)
+	return (*Graph)(G), err
+}
+
+// Write the graph in the DIMACS format to w. Note, the vertices are numbered
+// from 1 in the file.
+func (g *Digraph) WriteDIMACS(w io.Writer) error {
+	return writeGraph((*C.struct_bliss_graph_struct)(g), w, dimacs)
+}
+
+// Write the graph in the graphviz dot language to w.
+func (g *Digraph) WriteDot(w io.Writer) error {
+	return writeGraph((*C.struct_bliss_graph_struct)(g), w, dot)
+}
+
+// Write the graph in the DIMACS format to w. See Digraph.WriteDIMACS.
+func (g *Graph) WriteDIMACS(w io.Writer) error {
+	return writeGraph((*C.struct_bliss_graph_struct)(g), w, dimacs)
+}
+
+// Write the graph in the graphviz dot language to w.
+func (g *Graph) WriteDot(w io.Writer) error {
+	return writeGraph((*C.struct_bliss_graph_struct)(g), w, dot)
+}
+
+func readDIMACS(r io.Reader, directed bool) (*C.struct_bliss_graph_struct, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, errors.New("bliss: DIMACS: empty input")
+	}
+	buf := C.CBytes(data)
+	defer C.free(buf)
+	mode := C.CString("r")
+	defer C.free(unsafe.Pointer(mode))
+	fp := C.fmemopen(buf, C.size_t(len(data)), mode)
+	if fp == nil {
+		return nil, errors.New("bliss: DIMACS: could not open the input")
+	}
+	defer C.fclose(fp)
+	var G *C.struct_bliss_graph_struct
+	msg, err := memstream(func(errstr *C.FILE) {
+		if directed {
+			G = C.bliss_read_dimacs(fp, errstr)
+		} else {
+			G = C.bliss_read_dimacs_undirected(fp, errstr)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if G == nil {
+		return nil, fmt.Errorf("bliss: DIMACS: %v", strings.TrimSpace(string(msg)))
+	}
+	return G, nil
+}
+
+const (
+	dimacs = iota
+	dot
+)
+
+func writeGraph(G *C.struct_bliss_graph_struct, w io.Writer, format int) error {
+	out, err := memstream(func(fp *C.FILE) {
+		switch format {
+		case dimacs:
+			C.bliss_write_dimacs(G, fp)
+		case dot:
+			C.bliss_write_dot(G, fp)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// Runs the block with an in memory C stream and returns what was written to
+// it.
+func memstream(block func(*C.FILE)) ([]byte, error) {
+	// the stream updates buf and size after open_memstream returns so they
+	// must live in C memory.
+	buf := (**C.char)(C.calloc(1, C.size_t(unsafe.Sizeof((*C.char)(nil)))))
+	defer C.free(unsafe.Pointer(buf))
+	size := (*C.size_t)(C.calloc(1, C.size_t(unsafe.Sizeof(C.size_t(0)))))
+	defer C.free(unsafe.Pointer(size))
+	fp := C.open_memstream(buf, size)
+	if fp == nil {
+		return nil, errors.New("bliss: could not open an in memory stream")
+	}
+	block(fp)
+	C.fclose(fp)
+	defer C.free(unsafe.Pointer(*buf))
+	return C.GoBytes(unsafe.Pointer(*buf), C.int(*size)), nil
+}
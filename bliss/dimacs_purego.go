@@ -0,0 +1,185 @@
+//go:build !cgo || purego
+
+package bliss
+
+/*
+  Copyright (c) 2016 Tim Henderson
+  Released under the GNU General Public License version 3.
+
+  This file is part of goiso a wrapper around bliss.
+
+  bliss is free software: you can redistribute it and/or modify
+  it under the terms of the GNU General Public License version 3
+  as published by the Free Software Foundation.
+
+  bliss is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU General Public License for more details.
+
+  You should have received a copy of the GNU General Public License
+  along with .  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Read a directed graph in the DIMACS format used by the standalone bliss
+// tool (see http://www.tcs.hut.fi/Software/bliss/fileformat.shtml).
+func ReadDIMACS(r io.Reader) (*Digraph, error) {
+	G, err := readDIMACS(r, true)
+	return (*Digraph)(G), err
+}
+
+// The same as ReadDIMACS except the graph read is undirected.
+func ReadDIMACSGraph(r io.Reader) (*Graph, error) {
+	G, err := readDIMACS(r, false)
+	return (*Graph)(G), err
+}
+
+// Write the graph in the DIMACS format to w. Note, the vertices are numbered
+// from 1 in the file.
+func (g *Digraph) WriteDIMACS(w io.Writer) error {
+	return (*goGraph)(g).writeDIMACS(w)
+}
+
+// Write the graph in the graphviz dot language to w.
+func (g *Digraph) WriteDot(w io.Writer) error {
+	return (*goGraph)(g).writeDot(w)
+}
+
+// Write the graph in the DIMACS format to w. See Digraph.WriteDIMACS.
+func (g *Graph) WriteDIMACS(w io.Writer) error {
+	return (*goGraph)(g).writeDIMACS(w)
+}
+
+// Write the graph in the graphviz dot language to w.
+func (g *Graph) WriteDot(w io.Writer) error {
+	return (*goGraph)(g).writeDot(w)
+}
+
+// Reads the same subset of DIMACS as bliss: comments, then the problem line,
+// then the vertex colors, then exactly the announced number of edges.
+func readDIMACS(r io.Reader, directed bool) (*goGraph, error) {
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	next := func() (string, bool) {
+		if !scanner.Scan() {
+			return "", false
+		}
+		lineNum++
+		return scanner.Text(), true
+	}
+	formatErr := func() error {
+		return fmt.Errorf("bliss: DIMACS: error in line %v: not in DIMACS format", lineNum)
+	}
+	var nofVertices, nofEdges uint
+	line, ok := next()
+	for ok && strings.HasPrefix(line, "c") {
+		line, ok = next()
+	}
+	if !ok {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		if lineNum == 0 {
+			return nil, errors.New("bliss: DIMACS: empty input")
+		}
+		lineNum++
+		return nil, formatErr()
+	}
+	if _, err := fmt.Sscanf(line, "p edge %d %d", &nofVertices, &nofEdges); err != nil {
+		return nil, formatErr()
+	}
+	if nofVertices == 0 {
+		return nil, errors.New("bliss: DIMACS: error: no vertices")
+	}
+	g := newGoGraph(int(nofVertices), directed)
+	vertex := func(v uint) (uint, error) {
+		if v < 1 || v > nofVertices {
+			return 0, fmt.Errorf(
+				"bliss: DIMACS: error in line %v: vertex %v not in range [1,...%v]",
+				lineNum, v, nofVertices)
+		}
+		return v - 1, nil
+	}
+	line, ok = next()
+	for ok && strings.HasPrefix(line, "n") {
+		var v, color uint
+		if _, err := fmt.Sscanf(line, "n %d %d", &v, &color); err != nil {
+			return nil, formatErr()
+		}
+		v, err := vertex(v)
+		if err != nil {
+			return nil, err
+		}
+		g.nodes[v] = uint32(color)
+		line, ok = next()
+	}
+	for i := uint(0); i < nofEdges; i++ {
+		if !ok {
+			lineNum++
+			return nil, formatErr()
+		}
+		var a, b uint
+		if _, err := fmt.Sscanf(line, "e %d %d", &a, &b); err != nil {
+			return nil, formatErr()
+		}
+		a, err := vertex(a)
+		if err != nil {
+			return nil, err
+		}
+		b, err = vertex(b)
+		if err != nil {
+			return nil, err
+		}
+		g.addEdge(a, b)
+		line, ok = next()
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (g *goGraph) writeDIMACS(w io.Writer) error {
+	var buf bytes.Buffer
+	edges := g.sortedEdges()
+	fmt.Fprintf(&buf, "p edge %d %d\n", len(g.nodes), len(edges))
+	for v, color := range g.nodes {
+		fmt.Fprintf(&buf, "n %d %d\n", v+1, color)
+	}
+	for _, e := range edges {
+		fmt.Fprintf(&buf, "e %d %d\n", e.Src+1, e.Targ+1)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func (g *goGraph) writeDot(w io.Writer) error {
+	var buf bytes.Buffer
+	arrow := "--"
+	if g.directed {
+		fmt.Fprintf(&buf, "digraph g {\n")
+		arrow = "->"
+	} else {
+		fmt.Fprintf(&buf, "graph g {\n")
+	}
+	edges := g.sortedEdges()
+	for v, color := range g.nodes {
+		fmt.Fprintf(&buf, "v%d [label=\"%d:%d\"];\n", v, v, color)
+		for len(edges) > 0 && int(edges[0].Src) == v {
+			fmt.Fprintf(&buf, "v%d %s v%d\n", edges[0].Src, arrow, edges[0].Targ)
+			edges = edges[1:]
+		}
+	}
+	fmt.Fprintf(&buf, "}\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
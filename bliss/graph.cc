@@ -1,3 +1,5 @@
+//go:build cgo && !purego
+
 #include <cstdio>
 #include <cassert>
 #include <climits>
@@ -61,6 +63,11 @@ AbstractGraph::AbstractGraph()
   /* Default value for using component recursion */
   opt_use_comprec = true;
 
+  /* By default the search is never abandoned */
+  abort_flag = 0;
+  max_nodes = 0;
+  search_aborted = false;
+
 
   verbose_level = 0;
   verbstr = stdout;
@@ -652,6 +659,7 @@ AbstractGraph::search(const bool canonical, Stats& stats)
   stats.reset();
   stats.nof_nodes = 1;
   stats.nof_leaf_nodes = 1;
+  search_aborted = false;
 
   /* Free old first path data structures */
   if(first_path_labeling) {
@@ -850,6 +858,12 @@ AbstractGraph::search(const bool canonical, Stats& stats)
    */
   while(!search_stack.empty()) 
     {
+      if(search_should_abort(stats))
+        {
+          search_aborted = true;
+          break;
+        }
+
       TreeNode&          current_node  = search_stack.back();
       const unsigned int current_level = (unsigned int)search_stack.size()-1;
 
@@ -1763,6 +1777,22 @@ AbstractGraph::search(const bool canonical, Stats& stats)
   /* Release component recursion data in partition */
   if(opt_use_comprec)
     p.cr_free();
+
+  /* The search is over, the options may be changed again */
+  in_search = false;
+}
+
+
+
+
+bool
+AbstractGraph::search_should_abort(const Stats& stats) const
+{
+  if(abort_flag and __atomic_load_n(abort_flag, __ATOMIC_RELAXED) != 0)
+    return true;
+  if(max_nodes != 0 and stats.nof_nodes > max_nodes)
+    return true;
+  return false;
 }
 
 
@@ -2349,6 +2379,29 @@ Digraph::read_dimacs(FILE* const fp, FILE* const errstr)
 
 
 
+void
+Digraph::get_edges(std::vector<unsigned int>& ends)
+{
+  remove_duplicate_edges();
+  sort_edges();
+
+  for(unsigned int i = 0; i < get_nof_vertices(); i++)
+    {
+      Vertex &v = vertices[i];
+      for(std::vector<unsigned int>::const_iterator ei = v.edges_out.begin();
+          ei != v.edges_out.end();
+          ei++)
+        {
+          ends.push_back(i);
+          ends.push_back(*ei);
+        }
+    }
+}
+
+
+
+
+
 void
 Digraph::write_dimacs(FILE* const fp)
 {
@@ -4194,6 +4247,32 @@ Graph::read_dimacs(FILE* const fp, FILE* const errstr)
 }
 
 
+void
+Graph::get_edges(std::vector<unsigned int>& ends)
+{
+  remove_duplicate_edges();
+  sort_edges();
+
+  for(unsigned int i = 0; i < get_nof_vertices(); i++)
+    {
+      Vertex &v = vertices[i];
+      for(std::vector<unsigned int>::const_iterator ei = v.edges.begin();
+          ei != v.edges.end();
+          ei++)
+        {
+          const unsigned int dest_i = *ei;
+          if(dest_i < i)
+            continue;
+          ends.push_back(i);
+          ends.push_back(dest_i);
+        }
+    }
+}
+
+
+
+
+
 void
 Graph::write_dimacs(FILE* const fp)
 {
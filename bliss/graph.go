@@ -0,0 +1,150 @@
+//go:build cgo && !purego
+
+package bliss
+
+/*
+  Copyright (c) 2016 Tim Henderson
+  Released under the GNU General Public License version 3.
+
+  This file is part of goiso a wrapper around bliss.
+
+  bliss is free software: you can redistribute it and/or modify
+  it under the terms of the GNU General Public License version 3
+  as published by the Free Software Foundation.
+
+  bliss is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU General Public License for more details.
+
+  You should have received a copy of the GNU General Public License
+  along with goiso.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/*
+#include "bliss_C.h"
+*/
+import "C"
+
+// An undirected bliss graph. It has the same interface as the Digraph.
+type Graph C.struct_bliss_graph_struct
+
+// A context manager which release the undirected graph after the block
+// ends.
+func DoGraph(nodes int, block func(*Graph)) {
+	g := NewGraph(nodes)
+	defer g.Release()
+	block(g)
+}
+
+// Constructs a new bliss graph object. Note, this
+// is an undirected graph. See NewDigraph for directed graphs.
+// nodes = the number of nodes to add with color 0
+func NewGraph(nodes int) *Graph {
+	n := C.uint(uint(nodes))
+	return (*Graph)(C.bliss_new_undirected(n))
+}
+
+// Release the graph. You need to manage the memory manually
+// as the graph lives in C land.
+func (g *Graph) Release() {
+	G := (*C.struct_bliss_graph_struct)(g)
+	C.bliss_release(G)
+}
+
+// Set the options used by all subsequent searches on the graph. See
+// Digraph.SetOptions.
+func (g *Graph) SetOptions(opts *Options) {
+	setOptions((*C.struct_bliss_graph_struct)(g), opts)
+}
+
+// Add a new vertex of the given color to the graph.
+// The vertex id will be returned.
+func (g *Graph) AddVertex(color uint) uint {
+	c := C.uint(color)
+	G := (*C.struct_bliss_graph_struct)(g)
+	return uint(C.bliss_add_vertex(G, c))
+}
+
+// Add a new edge between the two vertex ids
+// Since this is an undirected graph: a -- b
+func (g *Graph) AddEdge(a, b uint) {
+	x := C.uint(a)
+	y := C.uint(b)
+	G := (*C.struct_bliss_graph_struct)(g)
+	C.bliss_add_edge(G, x, y)
+}
+
+// Compare two graphs. See Digraph.Cmp.
+func (a *Graph) Cmp(b *Graph) int {
+	g1 := (*C.struct_bliss_graph_struct)(a)
+	g2 := (*C.struct_bliss_graph_struct)(b)
+	return int(C.bliss_cmp(g1, g2))
+}
+
+// Are the graphs isomorphic? See Digraph.Iso.
+func (a *Graph) Iso(b *Graph) bool {
+	var cmp bool
+	a.CanonicalCtx(func(g1 *Graph) {
+		b.CanonicalCtx(func(g2 *Graph) {
+			cmp = g1.Cmp(g2) == 0
+		})
+	})
+	return cmp
+}
+
+// Compute the canonical labeling. This will function will
+// return a new *Graph which must also be released.
+func (g *Graph) Canonical() *Graph {
+	G := (*C.struct_bliss_graph_struct)(g)
+	p := C.bliss_find_canonical_labeling(G, nil, nil, nil)
+	return (*Graph)(C.bliss_permute(G, p))
+}
+
+// The same as Canonical but additionally returns the statistics of the
+// search.
+func (g *Graph) CanonicalStats() (*Graph, *Stats) {
+	var s C.BlissStats
+	G := (*C.struct_bliss_graph_struct)(g)
+	p := C.bliss_find_canonical_labeling(G, nil, nil, &s)
+	return (*Graph)(C.bliss_permute(G, p)), newStats(&s)
+}
+
+// A context manager for Canonical graph.
+func (g *Graph) CanonicalCtx(block func(*Graph)) {
+	can := g.Canonical()
+	defer can.Release()
+	block(can)
+}
+
+// Compute the permutation. Returns a slice of new indexes. Read the slice as:
+// mapping[original-index-for-v] -> new-index-for-v
+func (g *Graph) CanonicalPermutation() (mapping []uint) {
+	return canonicalPermutation((*C.struct_bliss_graph_struct)(g), nil)
+}
+
+// The same as CanonicalPermutation but additionally returns the statistics
+// of the search.
+func (g *Graph) CanonicalPermutationStats() (mapping []uint, stats *Stats) {
+	var s C.BlissStats
+	mapping = canonicalPermutation((*C.struct_bliss_graph_struct)(g), &s)
+	return mapping, newStats(&s)
+}
+
+// Compute a set of generators for the automorphism group of the graph. See
+// Digraph.Automorphisms.
+func (g *Graph) Automorphisms() (generators [][]uint) {
+	return graphAutomorphisms((*C.struct_bliss_graph_struct)(g))
+}
+
+// Computes the orbit partition of the vertices. See Digraph.Orbits.
+func (g *Graph) Orbits() (orbits []int) {
+	G := (*C.struct_bliss_graph_struct)(g)
+	return uintOrbitPartition(int(C.bliss_get_nof_vertices(G)), graphAutomorphisms(G))
+}
+
+// The number of vertices and the edges (without duplicates) of the graph.
+// Each edge is given once with the smaller vertex as its Src.
+func (g *Graph) edgeList() (n int, edges []BlissEdge) {
+	return graphEdges((*C.struct_bliss_graph_struct)(g))
+}
@@ -95,6 +95,8 @@ public:
   /** An approximation (due to possible overflows/rounding errors) of
    * the size of the automorphism group. */
   long double get_group_size_approx() const {return group_size_approx;}
+  /** The size of the automorphism group as a string of decimal digits. */
+  std::string get_group_size() const {return group_size.str();}
   /** The number of nodes in the search tree. */
   long unsigned int get_nof_nodes() const {return nof_nodes;}
   /** The number of leaf nodes in the search tree. */
@@ -152,6 +154,23 @@ protected:
    */
   bool opt_use_comprec;
 
+  /** \internal
+   * If non-null the search is abandoned once *abort_flag becomes non-zero.
+   */
+  const int *abort_flag;
+  /** \internal
+   * If non-zero the search is abandoned after visiting this many nodes.
+   */
+  unsigned long int max_nodes;
+  /** \internal
+   * Was the last search abandoned before it completed?
+   */
+  bool search_aborted;
+  /** \internal
+   * Should the search be abandoned?
+   */
+  bool search_should_abort(const Stats& stats) const;
+
 
   unsigned int refine_current_path_certificate_index;
   bool refine_compare_certificate;
@@ -464,6 +483,15 @@ public:
    */
   virtual void write_dimacs(FILE * const fp) = 0;
 
+  /**
+   * Append the edges of the graph to \a ends, each one as its source
+   * followed by its target. As in write_dimacs() the duplicate edges are
+   * removed first. An undirected edge is listed once with the smaller
+   * vertex first.
+   * \param ends  the vector the vertex pairs are appended to
+   */
+  virtual void get_edges(std::vector<unsigned int>& ends) = 0;
+
   /**
    * Write the graph to a file in the graphviz dotty format.
    * \param fp  the file stream where the graph is written
@@ -498,6 +526,26 @@ public:
     opt_use_long_prune = active;
   }
 
+  /**
+   * Set the limits of the search. The search is abandoned as soon as the
+   * value pointed to by \a flag (if non-null) becomes non-zero, which may be
+   * done from another thread, or once more than \a nodes nodes of the search
+   * tree have been visited (if \a nodes is non-zero). Use search_aborted()
+   * to find out whether the last search completed. The results of an
+   * abandoned search (canonical labeling, statistics) are not valid.
+   */
+  void set_search_limits(const int *flag, const unsigned long int nodes) {
+    assert(!in_search);
+    abort_flag = flag;
+    max_nodes = nodes;
+  }
+
+  /**
+   * Was the last search abandoned because of the limits set with
+   * set_search_limits()?
+   */
+  bool was_search_aborted() const {return search_aborted; }
+
 };
 
 
@@ -658,6 +706,11 @@ public:
    */
   void write_dimacs(FILE* const fp);
 
+  /**
+   * \copydoc AbstractGraph::get_edges(std::vector<unsigned int>& ends)
+   */
+  void get_edges(std::vector<unsigned int>& ends);
+
   /**
    * \copydoc AbstractGraph::write_dot(FILE * const fp)
    */
@@ -895,6 +948,11 @@ public:
    */
   void write_dimacs(FILE* const fp);
 
+  /**
+   * \copydoc AbstractGraph::get_edges(std::vector<unsigned int>& ends)
+   */
+  void get_edges(std::vector<unsigned int>& ends);
+
 
   /**
    * \copydoc AbstractGraph::write_dot(FILE *fp)
@@ -0,0 +1,422 @@
+package bliss
+
+/*
+  Copyright (c) 2016 Tim Henderson
+  Released under the GNU General Public License version 3.
+
+  This file is part of goiso a wrapper around bliss.
+
+  bliss is free software: you can redistribute it and/or modify
+  it under the terms of the GNU General Public License version 3
+  as published by the Free Software Foundation.
+
+  bliss is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU General Public License for more details.
+
+  You should have received a copy of the GNU General Public License
+  along with .  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// The formats of the graph6 family used by nauty and most graph collections
+// (see http://users.cecs.anu.edu.au/~bdm/data/formats.txt). Each graph is
+// one line of printable ASCII.
+type Graph6Format int
+
+const (
+	Graph6   Graph6Format = iota // undirected simple graphs
+	Sparse6                      // undirected graphs with loops and parallel edges
+	Digraph6                     // directed graphs with loops
+)
+
+func (f Graph6Format) String() string {
+	switch f {
+	case Graph6:
+		return "graph6"
+	case Sparse6:
+		return "sparse6"
+	case Digraph6:
+		return "digraph6"
+	}
+	return fmt.Sprintf("Graph6Format(%d)", int(f))
+}
+
+// Encodes the graph with n vertices as one line (without the newline). For
+// Graph6 and Sparse6 the direction of the edges is ignored. Graph6 drops
+// loops and Graph6 and Digraph6 drop parallel edges.
+func EncodeGraph6(format Graph6Format, n int, edges []BlissEdge) []byte {
+	var buf []byte
+	switch format {
+	case Graph6:
+		bits := newBitWriter(appendGraph6N(buf, n))
+		adj := make([]bool, n*(n-1)/2)
+		for _, e := range edges {
+			i, j := int(e.Src), int(e.Targ)
+			if i > j {
+				i, j = j, i
+			}
+			if i != j {
+				adj[j*(j-1)/2+i] = true
+			}
+		}
+		for _, b := range adj {
+			bits.write(b)
+		}
+		return bits.pad(false)
+	case Digraph6:
+		bits := newBitWriter(appendGraph6N(append(buf, '&'), n))
+		adj := make([]bool, n*n)
+		for _, e := range edges {
+			adj[int(e.Src)*n+int(e.Targ)] = true
+		}
+		for _, b := range adj {
+			bits.write(b)
+		}
+		return bits.pad(false)
+	case Sparse6:
+		return encodeSparse6(n, edges)
+	}
+	panic(fmt.Errorf("unknown graph6 format %v", format))
+}
+
+// The largest vertex count DecodeGraph6 accepts. The count comes first on a
+// line so without a bound a short line could make a reader allocate a huge
+// graph (a sparse6 line does not need a bit per vertex). Raise it to read
+// larger graphs.
+var MaxGraph6Vertices = 1 << 24
+
+// Decodes one line in any of the graph6 family of formats. The format is
+// detected from the line's prefix (an optional >>graph6<< style header is
+// skipped). Undirected edges have Src <= Targ. Lines with more than
+// MaxGraph6Vertices vertices are rejected.
+func DecodeGraph6(line []byte) (format Graph6Format, n int, edges []BlissEdge, err error) {
+	line = bytes.TrimRight(line, "\r\n")
+	for _, header := range []string{">>graph6<<", ">>sparse6<<", ">>digraph6<<"} {
+		line = bytes.TrimPrefix(line, []byte(header))
+	}
+	if len(line) == 0 {
+		return 0, 0, nil, errors.New("empty graph")
+	}
+	format = Graph6
+	switch line[0] {
+	case ':':
+		format = Sparse6
+		line = line[1:]
+	case '&':
+		format = Digraph6
+		line = line[1:]
+	case ';':
+		return 0, 0, nil, errors.New("incremental sparse6 is not supported")
+	}
+	for _, c := range line {
+		if c < 63 || c > 126 {
+			return 0, 0, nil, fmt.Errorf("invalid character %q", c)
+		}
+	}
+	n, line, err = decodeGraph6N(line)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	// the sizes are computed in 64 bits where the bounded n can not
+	// overflow them
+	N := uint64(n)
+	bits := &bitReader{data: line}
+	switch format {
+	case Graph6:
+		var pairs uint64
+		if n > 0 {
+			pairs = N * (N - 1) / 2
+		}
+		if expected := (pairs + 5) / 6; uint64(len(line)) != expected {
+			return 0, 0, nil, fmt.Errorf("expected %v bytes of edges got %v", expected, len(line))
+		}
+		for j := 1; j < n; j++ {
+			for i := 0; i < j; i++ {
+				if bits.read(1) == 1 {
+					edges = append(edges, BlissEdge{uint32(i), uint32(j)})
+				}
+			}
+		}
+	case Digraph6:
+		if expected := (N*N + 5) / 6; uint64(len(line)) != expected {
+			return 0, 0, nil, fmt.Errorf("expected %v bytes of edges got %v", expected, len(line))
+		}
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				if bits.read(1) == 1 {
+					edges = append(edges, BlissEdge{uint32(i), uint32(j)})
+				}
+			}
+		}
+	case Sparse6:
+		k := sparse6Width(n)
+		v := 0
+		for bits.remaining() >= 1+k {
+			b := bits.read(1)
+			x := bits.read(k)
+			if b == 1 {
+				v++
+			}
+			if v >= n {
+				break
+			}
+			if x > v {
+				v = x
+			} else {
+				edges = append(edges, BlissEdge{uint32(x), uint32(v)})
+			}
+		}
+	}
+	return format, n, edges, nil
+}
+
+func encodeSparse6(n int, edges []BlissEdge) []byte {
+	sorted := make([]BlissEdge, 0, len(edges))
+	for _, e := range edges {
+		if e.Src > e.Targ {
+			e.Src, e.Targ = e.Targ, e.Src
+		}
+		sorted = append(sorted, e)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Targ != sorted[j].Targ {
+			return sorted[i].Targ < sorted[j].Targ
+		}
+		return sorted[i].Src < sorted[j].Src
+	})
+	k := sparse6Width(n)
+	bits := newBitWriter(appendGraph6N([]byte{':'}, n))
+	writeX := func(x int) {
+		for s := k - 1; s >= 0; s-- {
+			bits.write(x>>uint(s)&1 == 1)
+		}
+	}
+	v := 0
+	for _, e := range sorted {
+		i, j := int(e.Src), int(e.Targ)
+		if j == v {
+			bits.write(false)
+			writeX(i)
+		} else if j == v+1 {
+			bits.write(true)
+			writeX(i)
+		} else {
+			bits.write(true)
+			writeX(j)
+			bits.write(false)
+			writeX(i)
+		}
+		v = j
+	}
+	// Pad with ones. When the padding could be read as an edge to vertex
+	// n-1 it starts with a zero instead (this is what nauty does).
+	padding := (6 - bits.nbits%6) % 6
+	zero := k < 6 && n == 1<<uint(k) && v == n-2 && padding >= k+1
+	return bits.padSparse6(zero)
+}
+
+// The number of bits in a sparse6 vertex number.
+func sparse6Width(n int) int {
+	k := 0
+	for i := n - 1; i > 0; i >>= 1 {
+		k++
+	}
+	return k
+}
+
+func appendGraph6N(buf []byte, n int) []byte {
+	switch {
+	case n <= 62:
+		return append(buf, byte(n+63))
+	case n <= 258047:
+		return append(buf, 126, byte(n>>12&63+63), byte(n>>6&63+63), byte(n&63+63))
+	}
+	buf = append(buf, 126, 126)
+	for s := 30; s >= 0; s -= 6 {
+		buf = append(buf, byte(n>>uint(s)&63+63))
+	}
+	return buf
+}
+
+func decodeGraph6N(data []byte) (n int, rest []byte, err error) {
+	size := 1
+	switch {
+	case len(data) >= 2 && data[0] == 126 && data[1] == 126:
+		data, size = data[2:], 6
+	case len(data) >= 1 && data[0] == 126:
+		data, size = data[1:], 3
+	}
+	if len(data) < size {
+		return 0, nil, errors.New("truncated vertex count")
+	}
+	var N uint64
+	for _, c := range data[:size] {
+		N = N<<6 | uint64(c-63)
+	}
+	if N > uint64(MaxGraph6Vertices) {
+		return 0, nil, fmt.Errorf("%v vertices exceeds the maximum of %v", N, MaxGraph6Vertices)
+	}
+	return int(N), data[size:], nil
+}
+
+type bitWriter struct {
+	buf   []byte
+	cur   byte
+	nbits int
+}
+
+func newBitWriter(buf []byte) *bitWriter {
+	return &bitWriter{buf: buf}
+}
+
+func (w *bitWriter) write(b bool) {
+	w.cur <<= 1
+	if b {
+		w.cur |= 1
+	}
+	w.nbits++
+	if w.nbits%6 == 0 {
+		w.buf = append(w.buf, w.cur+63)
+		w.cur = 0
+	}
+}
+
+// Fills the last byte with b and returns the encoded bytes.
+func (w *bitWriter) pad(b bool) []byte {
+	for w.nbits%6 != 0 {
+		w.write(b)
+	}
+	return w.buf
+}
+
+func (w *bitWriter) padSparse6(zero bool) []byte {
+	if w.nbits%6 != 0 && zero {
+		w.write(false)
+	}
+	return w.pad(true)
+}
+
+type bitReader struct {
+	data []byte
+	bit  int
+}
+
+func (r *bitReader) remaining() int {
+	return 6*len(r.data) - r.bit
+}
+
+func (r *bitReader) read(k int) int {
+	x := 0
+	for ; k > 0; k-- {
+		c := r.data[r.bit/6] - 63
+		x = x<<1 | int(c>>uint(5-r.bit%6)&1)
+		r.bit++
+	}
+	return x
+}
+
+// Reads a file of graph6, sparse6 and digraph6 lines. Blank lines are
+// skipped. Errors carry the line number.
+func ReadGraph6Lines(r io.Reader, graph func(format Graph6Format, n int, edges []BlissEdge) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		format, n, edges, err := DecodeGraph6(line)
+		if err == nil {
+			err = graph(format, n, edges)
+		}
+		if err != nil {
+			return fmt.Errorf("bliss: graph6: error in line %v: %v", lineNum, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// Reads undirected graphs in the graph6 or sparse6 formats. The vertices all
+// have color 0. The graphs must be released.
+func ReadGraph6(r io.Reader) ([]*Graph, error) {
+	var graphs []*Graph
+	err := ReadGraph6Lines(r, func(format Graph6Format, n int, edges []BlissEdge) error {
+		if format == Digraph6 {
+			return errors.New("a digraph6 graph is directed, use ReadDigraph6")
+		}
+		g := NewGraph(n)
+		for _, e := range edges {
+			g.AddEdge(uint(e.Src), uint(e.Targ))
+		}
+		graphs = append(graphs, g)
+		return nil
+	})
+	if err != nil {
+		for _, g := range graphs {
+			g.Release()
+		}
+		return nil, err
+	}
+	return graphs, nil
+}
+
+// Reads directed graphs in any of the graph6 family of formats. An
+// undirected edge becomes an arc in each direction. The vertices all have
+// color 0. The graphs must be released.
+func ReadDigraph6(r io.Reader) ([]*Digraph, error) {
+	var graphs []*Digraph
+	err := ReadGraph6Lines(r, func(format Graph6Format, n int, edges []BlissEdge) error {
+		g := NewDigraph(n)
+		for _, e := range edges {
+			g.AddEdge(uint(e.Src), uint(e.Targ))
+			if format != Digraph6 && e.Src != e.Targ {
+				g.AddEdge(uint(e.Targ), uint(e.Src))
+			}
+		}
+		graphs = append(graphs, g)
+		return nil
+	})
+	if err != nil {
+		for _, g := range graphs {
+			g.Release()
+		}
+		return nil, err
+	}
+	return graphs, nil
+}
+
+// Write the graph as a line of digraph6. The vertex colors are dropped. To
+// write the canonical form use g.Canonical().WriteDigraph6(w).
+func (g *Digraph) WriteDigraph6(w io.Writer) error {
+	n, edges := g.edgeList()
+	return writeGraph6(w, Digraph6, n, edges)
+}
+
+// Write the graph as a line of graph6. The vertex colors are dropped.
+func (g *Graph) WriteGraph6(w io.Writer) error {
+	n, edges := g.edgeList()
+	return writeGraph6(w, Graph6, n, edges)
+}
+
+// Write the graph as a line of sparse6. The vertex colors are dropped.
+func (g *Graph) WriteSparse6(w io.Writer) error {
+	n, edges := g.edgeList()
+	return writeGraph6(w, Sparse6, n, edges)
+}
+
+func writeGraph6(w io.Writer, format Graph6Format, n int, edges []BlissEdge) error {
+	_, err := w.Write(append(EncodeGraph6(format, n, edges), '\n'))
+	return err
+}
@@ -1,3 +1,5 @@
+//go:build cgo && !purego
+
 #include <stdlib.h>
 #include <stdio.h>
 #include <limits.h>
@@ -20,6 +20,7 @@ package bliss
 */
 
 import (
+	"context"
 	"sort"
 )
 
@@ -27,11 +28,25 @@ import (
 // vertex labels. Bliss does not support edge labels so using a mapping is
 // necessary in order to canonically order edge labeled graphs.
 type Map struct {
-	LenV      int         // number of vertices in the original graph
-	LenE      int         // number of edges in the original graph
-	FirstEdge int         // index of the first vertex representing an edge
-	Nodes     []uint32    // The colors of each mapped vertex/edge
-	Edges     []BlissEdge // Mapped edges
+	LenV       int         // number of vertices in the original graph
+	LenE       int         // number of edges in the original graph
+	FirstEdge  int         // index of the first vertex representing an edge
+	Nodes      []uint32    // The colors of each mapped vertex/edge
+	Edges      []BlissEdge // Mapped edges
+	Undirected bool        // true if the original graph is undirected
+}
+
+type BlissEdge struct {
+	Src  uint32
+	Targ uint32
+}
+
+// One graph of a batch passed to CanonizeBatch. The edges refer to the
+// indices of the graph's own nodes.
+type BatchGraph struct {
+	Nodes      []uint32
+	Edges      []BlissEdge
+	Undirected bool
 }
 
 type VertexIterator func() (color int, vi VertexIterator)
@@ -71,6 +86,16 @@ func NewMap(lenV, lenE int, vi VertexIterator, ei EdgeIterator) *Map {
 	}
 }
 
+// Construct the Mapping of an undirected labeled graph. The src and targ of
+// each edge given by the iterator are interchangeable. Each edge becomes a
+// single vertex adjacent to both of its endpoints so undirected graphs no
+// longer need to be doubled into two arcs per edge.
+func NewUndirectedMap(lenV, lenE int, vi VertexIterator, ei EdgeIterator) *Map {
+	m := NewMap(lenV, lenE, vi, ei)
+	m.Undirected = true
+	return m
+}
+
 // Construct the CanonicalPermutation from the Map. The map itself is
 // unchanged the permutation is given in Vord and Eord. This method uses the
 // Canonize function and does not directly construct a bliss.Digraph. If you
@@ -83,7 +108,81 @@ func NewMap(lenV, lenE int, vi VertexIterator, ei EdgeIterator) *Map {
 //     and false otherwise
 //
 func (m *Map) CanonicalPermutation() (Vord, Eord []int, canonized bool) {
-	P := Canonize(m.Nodes, m.Edges)
+	P, _ := canonize(m.Nodes, m.Edges, !m.Undirected, nil, false)
+	return m.permutation(P)
+}
+
+// The same as CanonicalPermutation but additionally returns the statistics
+// of the bliss search.
+func (m *Map) CanonicalPermutationStats() (Vord, Eord []int, canonized bool, stats *Stats) {
+	return m.CanonicalPermutationOpts(nil)
+}
+
+// The same as CanonicalPermutationStats but the search is tuned with the
+// given options. If opts is nil the default options are used.
+func (m *Map) CanonicalPermutationOpts(opts *Options) (Vord, Eord []int, canonized bool, stats *Stats) {
+	P, stats := canonize(m.Nodes, m.Edges, !m.Undirected, opts, true)
+	Vord, Eord, canonized = m.permutation(P)
+	return Vord, Eord, canonized, stats
+}
+
+// The same as CanonicalPermutationOpts but the search stops early,
+// returning an *AbortError, if the context is canceled or one of the budgets
+// (MaxNodes, Timeout) in the options runs out.
+func (m *Map) CanonicalPermutationContext(ctx context.Context, opts *Options) (Vord, Eord []int, canonized bool, stats *Stats, err error) {
+	P, stats, err := canonizeContext(ctx, m.Nodes, m.Edges, !m.Undirected, opts)
+	if err != nil {
+		return nil, nil, false, nil, err
+	}
+	Vord, Eord, canonized = m.permutation(P)
+	return Vord, Eord, canonized, stats, nil
+}
+
+// Compute the CanonicalPermutation of many Maps at once. All of the maps are
+// canonized with a single cgo call (see CanonizeBatch) which saves the cgo
+// crossings but not the searches themselves. Vords[i], Eords[i] and
+// canonized[i] are read exactly as the return values of
+// maps[i].CanonicalPermutation(). A map the batch could not canonize is
+// canonized on its own so it behaves exactly as CanonicalPermutation does.
+func CanonicalPermutationBatch(maps []*Map) (Vords, Eords [][]int, canonized []bool) {
+	return CanonicalPermutationBatchOpts(maps, nil)
+}
+
+// The same as CanonicalPermutationBatch but every search is tuned with the
+// given options. If opts is nil the default options are used.
+func CanonicalPermutationBatchOpts(maps []*Map, opts *Options) (Vords, Eords [][]int, canonized []bool) {
+	graphs := make([]BatchGraph, 0, len(maps))
+	for _, m := range maps {
+		graphs = append(graphs, m.batchGraph())
+	}
+	Ps, errs := CanonizeBatchOpts(graphs, opts)
+	Vords = make([][]int, 0, len(maps))
+	Eords = make([][]int, 0, len(maps))
+	canonized = make([]bool, 0, len(maps))
+	for i, m := range maps {
+		P := Ps[i]
+		if errs != nil && errs[i] != nil {
+			P, _ = canonize(m.Nodes, m.Edges, !m.Undirected, opts, false)
+		}
+		Vord, Eord, c := m.permutation(P)
+		Vords = append(Vords, Vord)
+		Eords = append(Eords, Eord)
+		canonized = append(canonized, c)
+	}
+	return Vords, Eords, canonized
+}
+
+func (m *Map) batchGraph() BatchGraph {
+	return BatchGraph{
+		Nodes:      m.Nodes,
+		Edges:      m.Edges,
+		Undirected: m.Undirected,
+	}
+}
+
+// Splits the permutation of the mapped graph into the permutations of the
+// vertices and edges of the original graph.
+func (m *Map) permutation(P []uint) (Vord, Eord []int, canonized bool) {
 	VP := make(perms, 0, m.LenV)
 	EP := make(perms, 0, m.LenE)
 	canonized = true
@@ -118,7 +217,8 @@ func (m *Map) CanonicalPermutation() (Vord, Eord []int, canonized bool) {
 	return Vord, Eord, canonized
 }
 
-// Construct a BlissDigraph from the Map
+// Construct a BlissDigraph from the Map. If the Map is undirected use the
+// Graph method instead.
 func (m *Map) Digraph() *Digraph {
 	bg := NewDigraph(0)
 	for _, color := range m.Nodes {
@@ -129,3 +229,82 @@ func (m *Map) Digraph() *Digraph {
 	}
 	return bg
 }
+
+// Construct an undirected bliss Graph from the Map.
+func (m *Map) Graph() *Graph {
+	bg := NewGraph(0)
+	for _, color := range m.Nodes {
+		bg.AddVertex(uint(color))
+	}
+	for _, e := range m.Edges {
+		bg.AddEdge(uint(e.Src), uint(e.Targ))
+	}
+	return bg
+}
+
+// Compute a set of generators for the automorphism group of the labeled graph
+// the Map was constructed from. Each generator is split back into a vertex
+// permutation and an edge permutation of the original graph. Read the
+// returned variables as:
+//
+//   - Vauts[i][original-index] -> image of the vertex under generator i
+//   - Eauts[i][original-index] -> image of the edge under generator i
+//
+func (m *Map) Automorphisms() (Vauts, Eauts [][]int) {
+	gens := automorphisms(m.separatedNodes(), m.Edges, !m.Undirected, nil)
+	Vauts = make([][]int, 0, len(gens))
+	Eauts = make([][]int, 0, len(gens))
+	for _, aut := range gens {
+		vaut := make([]int, m.LenV)
+		eaut := make([]int, m.LenE)
+		for i, p := range aut {
+			if i < m.FirstEdge {
+				vaut[i] = int(p)
+			} else {
+				eaut[i-m.FirstEdge] = int(p) - m.FirstEdge
+			}
+		}
+		Vauts = append(Vauts, vaut)
+		Eauts = append(Eauts, eaut)
+	}
+	return Vauts, Eauts
+}
+
+// The colors of the mapped nodes with the vertices and edges placed in
+// disjoint color classes. Vertex and edge colors share a space in Nodes so
+// without this an automorphism could map a vertex onto an edge. The colors
+// are compacted to their rank first (vertex colors before edge colors) so
+// the separated colors never overflow even when the original colors use the
+// full 32 bits.
+func (m *Map) separatedNodes() []uint32 {
+	vcolors := make(map[uint32]uint32)
+	ecolors := make(map[uint32]uint32)
+	for i, color := range m.Nodes {
+		if i < m.FirstEdge {
+			vcolors[color] = 0
+		} else {
+			ecolors[color] = 0
+		}
+	}
+	rank := func(colors map[uint32]uint32, offset uint32) {
+		sorted := make([]int, 0, len(colors))
+		for color := range colors {
+			sorted = append(sorted, int(color))
+		}
+		sort.Ints(sorted)
+		for i, color := range sorted {
+			colors[uint32(color)] = offset + uint32(i)
+		}
+	}
+	rank(vcolors, 0)
+	rank(ecolors, uint32(len(vcolors)))
+	nodes := make([]uint32, len(m.Nodes))
+	for i, color := range m.Nodes {
+		if i < m.FirstEdge {
+			nodes[i] = vcolors[color]
+		} else {
+			nodes[i] = ecolors[color]
+		}
+	}
+	return nodes
+}
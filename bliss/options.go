@@ -0,0 +1,92 @@
+package bliss
+
+/*
+  Copyright (c) 2016 Tim Henderson
+  Released under the GNU General Public License version 3.
+
+  This file is part of goiso a wrapper around bliss.
+
+  bliss is free software: you can redistribute it and/or modify
+  it under the terms of the GNU General Public License version 3
+  as published by the Free Software Foundation.
+
+  bliss is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU General Public License for more details.
+
+  You should have received a copy of the GNU General Public License
+  along with goiso.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+import (
+	"fmt"
+	"time"
+)
+
+// The heuristic bliss uses to pick the cell of the partition to split (to
+// individualize a vertex from) at each level of the search. The heuristic
+// affects the computed canonical labeling. Graphs must be canonized with the
+// same heuristic for their canonical forms to be comparable.
+type SplittingHeuristic uint
+
+const (
+	// First non-unit cell. Very fast but may result in large search spaces
+	// on difficult graphs. Use for large but easy graphs.
+	SplitF SplittingHeuristic = iota
+	// First smallest non-unit cell.
+	SplitFS
+	// First largest non-unit cell.
+	SplitFL
+	// First maximally non-trivially connected non-unit cell.
+	SplitFM
+	// First smallest maximally non-trivially connected non-unit cell.
+	SplitFSM
+	// First largest maximally non-trivially connected non-unit cell. This is
+	// the bliss default.
+	SplitFLM
+)
+
+var heuristicNames = []string{"f", "fs", "fl", "fm", "fsm", "flm"}
+
+func (h SplittingHeuristic) String() string {
+	if int(h) < len(heuristicNames) {
+		return heuristicNames[h]
+	}
+	return fmt.Sprintf("SplittingHeuristic(%d)", uint(h))
+}
+
+// Options which tune the bliss search. A nil *Options means
+// DefaultOptions(). Like the splitting heuristic, component recursion
+// and long prune change the computed canonical labeling so use the same
+// Options for every graph you intend to compare.
+//
+// MaxNodes and Timeout are budgets for a single search. They are only
+// honored by the Context variants (CanonizeContext,
+// Map.CanonicalPermutationContext) which can report an AbortError. Zero
+// means no limit.
+type Options struct {
+	SplittingHeuristic SplittingHeuristic
+	ComponentRecursion bool
+	FailureRecording   bool
+	LongPrune          bool
+	MaxNodes           uint64        // the maximum number of search tree nodes
+	Timeout            time.Duration // the maximum duration of the search
+}
+
+// The options bliss uses when none are given.
+func DefaultOptions() *Options {
+	return &Options{
+		SplittingHeuristic: SplitFLM,
+		ComponentRecursion: true,
+		FailureRecording:   true,
+		LongPrune:          true,
+	}
+}
+
+func (o *Options) validate() error {
+	if o.SplittingHeuristic > SplitFLM {
+		return fmt.Errorf("bliss: invalid splitting heuristic %v", o.SplittingHeuristic)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package bliss
+
+/*
+  Copyright (c) 2016 Tim Henderson
+  Released under the GNU General Public License version 3.
+
+  This file is part of goiso a wrapper around bliss.
+
+  bliss is free software: you can redistribute it and/or modify
+  it under the terms of the GNU General Public License version 3
+  as published by the Free Software Foundation.
+
+  bliss is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU General Public License for more details.
+
+  You should have received a copy of the GNU General Public License
+  along with goiso.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Computes the orbit partition of the elements 0..n-1 under the group
+// generated by the given generators. Read the returned slice as:
+//
+//     orbits[element] -> smallest element in the same orbit
+//
+// So two elements are structurally equivalent iff their orbit ids are equal.
+func OrbitPartition(n int, generators [][]int) (orbits []int) {
+	parent := make([]int, n)
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for _, aut := range generators {
+		for i, j := range aut {
+			a, b := find(i), find(j)
+			// keep the smallest element as the root
+			if a < b {
+				parent[b] = a
+			} else if b < a {
+				parent[a] = b
+			}
+		}
+	}
+	orbits = make([]int, n)
+	for i := range orbits {
+		orbits[i] = find(i)
+	}
+	return orbits
+}
+
+func uintOrbitPartition(n int, generators [][]uint) (orbits []int) {
+	gens := make([][]int, 0, len(generators))
+	for _, aut := range generators {
+		gen := make([]int, len(aut))
+		for i, j := range aut {
+			gen[i] = int(j)
+		}
+		gens = append(gens, gen)
+	}
+	return OrbitPartition(n, gens)
+}
+
+// Computes the orbits of the vertices and edges of the labeled graph the Map
+// was constructed from. Read the returned variables as:
+//
+//   - Vorbits[original-index] -> smallest vertex index in the same orbit
+//   - Eorbits[original-index] -> smallest edge index in the same orbit
+//
+func (m *Map) Orbits() (Vorbits, Eorbits []int) {
+	Vauts, Eauts := m.Automorphisms()
+	return OrbitPartition(m.LenV, Vauts), OrbitPartition(m.LenE, Eauts)
+}
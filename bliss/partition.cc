@@ -1,3 +1,5 @@
+//go:build cgo && !purego
+
 #include <assert.h>
 #include <vector>
 #include <list>
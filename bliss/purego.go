@@ -0,0 +1,515 @@
+//go:build !cgo || purego
+
+package bliss
+
+/*
+  Copyright (c) 2016 Tim Henderson
+  Released under the GNU General Public License version 3.
+
+  This file is part of goiso a wrapper around bliss.
+
+  bliss is free software: you can redistribute it and/or modify
+  it under the terms of the GNU General Public License version 3
+  as published by the Free Software Foundation.
+
+  bliss is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU General Public License for more details.
+
+  You should have received a copy of the GNU General Public License
+  along with .  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// This file provides the package API on top of the pure Go search in
+// canon.go. It is built instead of the cgo wrapper around bliss when cgo is
+// disabled (CGO_ENABLED=0) or the purego build tag is given. The canonical
+// labelings it computes differ from the ones computed by bliss so do not mix
+// canonical forms computed by the two backends.
+
+// A directed graph. In the pure Go build the graph lives in Go memory.
+type Digraph goGraph
+
+// An undirected graph. It has the same interface as the Digraph.
+type Graph goGraph
+
+type goGraph struct {
+	nodes    []uint32
+	edges    []BlissEdge
+	directed bool
+	opts     *Options
+}
+
+// Construct and compute the canonical permutation of a digraph. Read the
+// returned slice as:
+//
+//     mapping[original-index-for-v] -> new-index-for-v
+//
+func Canonize(nodes []uint32, edges []BlissEdge) (mapping []uint) {
+	mapping, _ = canonize(nodes, edges, true, nil, false)
+	return mapping
+}
+
+// The same as Canonize but additionally returns the statistics of the search.
+func CanonizeStats(nodes []uint32, edges []BlissEdge) (mapping []uint, stats *Stats) {
+	return canonize(nodes, edges, true, nil, true)
+}
+
+// The same as CanonizeStats but the search is tuned with the given options.
+// If opts is nil the default options are used.
+func CanonizeOpts(nodes []uint32, edges []BlissEdge, opts *Options) (mapping []uint, stats *Stats) {
+	return canonize(nodes, edges, true, opts, true)
+}
+
+// The same as Canonize except the edges are undirected.
+func CanonizeUndirected(nodes []uint32, edges []BlissEdge) (mapping []uint) {
+	mapping, _ = canonize(nodes, edges, false, nil, false)
+	return mapping
+}
+
+// stats is only returned if withStats is true
+func canonize(nodes []uint32, edges []BlissEdge, directed bool, opts *Options, withStats bool) (mapping []uint, stats *Stats) {
+	if len(nodes) == 0 {
+		panic(fmt.Errorf("bliss: cannot canonize a graph with no vertices"))
+	}
+	mapping, _, stats, _ = searchCanonical(nodes, edges, directed, withoutBudgets(opts), nil)
+	if !withStats {
+		stats = nil
+	}
+	return mapping, stats
+}
+
+// Construct and compute the canonical permutation of a digraph. The search
+// stops early, returning an *AbortError, if the context is canceled or one of
+// the budgets (MaxNodes, Timeout) given in the options runs out.
+func CanonizeContext(ctx context.Context, nodes []uint32, edges []BlissEdge, opts *Options) (mapping []uint, stats *Stats, err error) {
+	return canonizeContext(ctx, nodes, edges, true, opts)
+}
+
+func canonizeContext(ctx context.Context, nodes []uint32, edges []BlissEdge, directed bool, opts *Options) (mapping []uint, stats *Stats, err error) {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+	if err := opts.validate(); err != nil {
+		return nil, nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, newAbortError(ctx, Canceled, nil)
+	}
+	if len(nodes) == 0 {
+		return nil, nil, fmt.Errorf("bliss: cannot canonize a graph with no vertices")
+	}
+	flag := new(int32)
+	stop := context.AfterFunc(ctx, func() {
+		atomic.CompareAndSwapInt32(flag, 0, int32(Canceled))
+	})
+	defer stop()
+	if opts.Timeout > 0 {
+		timer := time.AfterFunc(opts.Timeout, func() {
+			atomic.CompareAndSwapInt32(flag, 0, int32(TimedOut))
+		})
+		defer timer.Stop()
+	}
+	mapping, _, stats, reason := searchCanonical(nodes, edges, directed, opts, flag)
+	if reason != 0 {
+		return nil, nil, newAbortError(ctx, reason, stats)
+	}
+	return mapping, stats, nil
+}
+
+// Canonize many graphs with one call. The pure Go backend has no call
+// overhead to save so this is the same as canonizing each graph in turn.
+// Read the mappings as:
+//
+//     mappings[i][original-index-for-v] -> new-index-for-v of graphs[i]
+//
+// A graph which can not be canonized (for instance one without vertices)
+// does not fail the others. errs is nil if every graph was canonized,
+// otherwise errs[i] is non-nil and mappings[i] is nil for each graph which
+// failed.
+func CanonizeBatch(graphs []BatchGraph) (mappings [][]uint, errs []error) {
+	return CanonizeBatchOpts(graphs, nil)
+}
+
+// The same as CanonizeBatch but every search is tuned with the given
+// options. If opts is nil the default options are used. The budgets in the
+// options are not honored.
+func CanonizeBatchOpts(graphs []BatchGraph, opts *Options) (mappings [][]uint, errs []error) {
+	if len(graphs) == 0 {
+		return nil, nil
+	}
+	mappings = make([][]uint, 0, len(graphs))
+	for i := range graphs {
+		g := &graphs[i]
+		if len(g.Nodes) == 0 {
+			if errs == nil {
+				errs = make([]error, len(graphs))
+			}
+			errs[i] = fmt.Errorf("bliss: cannot canonize a graph with no vertices (graph %v of the batch)", i)
+			mappings = append(mappings, nil)
+			continue
+		}
+		mapping, _ := canonize(g.Nodes, g.Edges, !g.Undirected, opts, false)
+		mappings = append(mappings, mapping)
+	}
+	return mappings, errs
+}
+
+// Construct and compute a set of generators for the automorphism group of a
+// digraph. Read each returned generator as:
+//
+//     aut[v] -> image-of-v
+//
+func Automorphisms(nodes []uint32, edges []BlissEdge) (generators [][]uint) {
+	return automorphisms(nodes, edges, true, nil)
+}
+
+// The same as Automorphisms except the edges are undirected.
+func AutomorphismsUndirected(nodes []uint32, edges []BlissEdge) (generators [][]uint) {
+	return automorphisms(nodes, edges, false, nil)
+}
+
+func automorphisms(nodes []uint32, edges []BlissEdge, directed bool, opts *Options) (generators [][]uint) {
+	if len(nodes) == 0 {
+		return nil
+	}
+	_, generators, _, _ = searchCanonical(nodes, edges, directed, withoutBudgets(opts), nil)
+	return generators
+}
+
+// The budgets are only honored by the Context variants so they are dropped
+// everywhere else.
+func withoutBudgets(opts *Options) *Options {
+	if opts == nil {
+		return nil
+	}
+	o := *opts
+	o.MaxNodes = 0
+	o.Timeout = 0
+	return &o
+}
+
+// A context manager which release the graph after the block
+// ends.
+func Do(nodes int, block func(*Digraph)) {
+	g := NewDigraph(nodes)
+	defer g.Release()
+	block(g)
+}
+
+// Constructs a new digraph object. See NewGraph for undirected graphs.
+// nodes = the number of nodes to add with color 0
+func NewDigraph(nodes int) *Digraph {
+	return (*Digraph)(newGoGraph(nodes, true))
+}
+
+// A context manager which release the undirected graph after the block
+// ends.
+func DoGraph(nodes int, block func(*Graph)) {
+	g := NewGraph(nodes)
+	defer g.Release()
+	block(g)
+}
+
+// Constructs a new undirected graph object. See NewDigraph for directed
+// graphs.
+// nodes = the number of nodes to add with color 0
+func NewGraph(nodes int) *Graph {
+	return (*Graph)(newGoGraph(nodes, false))
+}
+
+func newGoGraph(nodes int, directed bool) *goGraph {
+	return &goGraph{
+		nodes:    make([]uint32, nodes),
+		directed: directed,
+	}
+}
+
+// Release the graph. The pure Go graph is garbage collected so this does
+// nothing. It is kept so code works with either backend.
+func (g *Digraph) Release() {}
+
+// Set the options used by all subsequent searches on the graph. A nil opts
+// restores the defaults.
+func (g *Digraph) SetOptions(opts *Options) {
+	(*goGraph)(g).setOptions(opts)
+}
+
+// Add a new vertex of the given color to the graph.
+// The vertex id will be returned.
+func (g *Digraph) AddVertex(color uint) uint {
+	return (*goGraph)(g).addVertex(color)
+}
+
+// Add a new edge between the two vertex ids
+// Since this is a directed graph: a -> b
+func (g *Digraph) AddEdge(a, b uint) {
+	(*goGraph)(g).addEdge(a, b)
+}
+
+// Compare two graphs.
+// If a < b: -1
+// If a = b: 0
+// If a > b: 1
+// Note, this does not check for isomorphism. To do that compare the
+// canonical graphs or use Iso.
+func (a *Digraph) Cmp(b *Digraph) int {
+	return (*goGraph)(a).cmp((*goGraph)(b))
+}
+
+// Are the graphs isomorphic?
+func (a *Digraph) Iso(b *Digraph) bool {
+	return a.Canonical().Cmp(b.Canonical()) == 0
+}
+
+// Compute the canonical labeling. Returns a new *Digraph.
+func (g *Digraph) Canonical() *Digraph {
+	can, _ := (*goGraph)(g).canonical()
+	return (*Digraph)(can)
+}
+
+// The same as Canonical but additionally returns the statistics of the
+// search.
+func (g *Digraph) CanonicalStats() (*Digraph, *Stats) {
+	can, stats := (*goGraph)(g).canonical()
+	return (*Digraph)(can), stats
+}
+
+// A context manager for Canonical graph.
+func (g *Digraph) CanonicalCtx(block func(*Digraph)) {
+	can := g.Canonical()
+	defer can.Release()
+	block(can)
+}
+
+// Compute the permutation. Returns a slice of new indexes. Read the slice as:
+// mapping[original-index-for-v] -> new-index-for-v
+func (g *Digraph) CanonicalPermutation() (mapping []uint) {
+	mapping, _ = (*goGraph)(g).canonicalPermutation()
+	return mapping
+}
+
+// The same as CanonicalPermutation but additionally returns the statistics
+// of the search.
+func (g *Digraph) CanonicalPermutationStats() (mapping []uint, stats *Stats) {
+	return (*goGraph)(g).canonicalPermutation()
+}
+
+// Compute a set of generators for the automorphism group of the graph. Read
+// each generator as:
+//
+//     aut[v] -> image-of-v
+//
+// The identity is never returned so a graph with no symmetries yields an
+// empty slice.
+func (g *Digraph) Automorphisms() (generators [][]uint) {
+	return (*goGraph)(g).automorphisms()
+}
+
+// Computes the orbit partition of the vertices. Read the returned slice as:
+//
+//     orbits[v] -> smallest vertex in the same orbit as v
+//
+func (g *Digraph) Orbits() (orbits []int) {
+	G := (*goGraph)(g)
+	return uintOrbitPartition(len(G.nodes), G.automorphisms())
+}
+
+// The number of vertices and the edges (without duplicates) of the graph.
+func (g *Digraph) edgeList() (n int, edges []BlissEdge) {
+	G := (*goGraph)(g)
+	return len(G.nodes), G.sortedEdges()
+}
+
+// Release the graph. See Digraph.Release.
+func (g *Graph) Release() {}
+
+// Set the options used by all subsequent searches on the graph. See
+// Digraph.SetOptions.
+func (g *Graph) SetOptions(opts *Options) {
+	(*goGraph)(g).setOptions(opts)
+}
+
+// Add a new vertex of the given color to the graph.
+// The vertex id will be returned.
+func (g *Graph) AddVertex(color uint) uint {
+	return (*goGraph)(g).addVertex(color)
+}
+
+// Add a new edge between the two vertex ids
+// Since this is an undirected graph: a -- b
+func (g *Graph) AddEdge(a, b uint) {
+	(*goGraph)(g).addEdge(a, b)
+}
+
+// Compare two graphs. See Digraph.Cmp.
+func (a *Graph) Cmp(b *Graph) int {
+	return (*goGraph)(a).cmp((*goGraph)(b))
+}
+
+// Are the graphs isomorphic?
+func (a *Graph) Iso(b *Graph) bool {
+	return a.Canonical().Cmp(b.Canonical()) == 0
+}
+
+// Compute the canonical labeling. Returns a new *Graph.
+func (g *Graph) Canonical() *Graph {
+	can, _ := (*goGraph)(g).canonical()
+	return (*Graph)(can)
+}
+
+// The same as Canonical but additionally returns the statistics of the
+// search.
+func (g *Graph) CanonicalStats() (*Graph, *Stats) {
+	can, stats := (*goGraph)(g).canonical()
+	return (*Graph)(can), stats
+}
+
+// A context manager for Canonical graph.
+func (g *Graph) CanonicalCtx(block func(*Graph)) {
+	can := g.Canonical()
+	defer can.Release()
+	block(can)
+}
+
+// Compute the permutation. Returns a slice of new indexes. Read the slice as:
+// mapping[original-index-for-v] -> new-index-for-v
+func (g *Graph) CanonicalPermutation() (mapping []uint) {
+	mapping, _ = (*goGraph)(g).canonicalPermutation()
+	return mapping
+}
+
+// The same as CanonicalPermutation but additionally returns the statistics
+// of the search.
+func (g *Graph) CanonicalPermutationStats() (mapping []uint, stats *Stats) {
+	return (*goGraph)(g).canonicalPermutation()
+}
+
+// Compute a set of generators for the automorphism group of the graph. See
+// Digraph.Automorphisms.
+func (g *Graph) Automorphisms() (generators [][]uint) {
+	return (*goGraph)(g).automorphisms()
+}
+
+// Computes the orbit partition of the vertices. See Digraph.Orbits.
+func (g *Graph) Orbits() (orbits []int) {
+	G := (*goGraph)(g)
+	return uintOrbitPartition(len(G.nodes), G.automorphisms())
+}
+
+// The number of vertices and the edges (without duplicates) of the graph.
+func (g *Graph) edgeList() (n int, edges []BlissEdge) {
+	G := (*goGraph)(g)
+	return len(G.nodes), G.sortedEdges()
+}
+
+func (g *goGraph) setOptions(opts *Options) {
+	if opts != nil {
+		if err := opts.validate(); err != nil {
+			panic(err)
+		}
+	}
+	g.opts = opts
+}
+
+func (g *goGraph) addVertex(color uint) uint {
+	g.nodes = append(g.nodes, uint32(color))
+	return uint(len(g.nodes) - 1)
+}
+
+func (g *goGraph) addEdge(a, b uint) {
+	if !g.directed && a > b {
+		a, b = b, a
+	}
+	g.edges = append(g.edges, BlissEdge{Src: uint32(a), Targ: uint32(b)})
+}
+
+// The edges sorted with the duplicates removed.
+func (g *goGraph) sortedEdges() []BlissEdge {
+	edges := append([]BlissEdge(nil), g.edges...)
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Src != edges[j].Src {
+			return edges[i].Src < edges[j].Src
+		}
+		return edges[i].Targ < edges[j].Targ
+	})
+	j := 0
+	for i, e := range edges {
+		if i == 0 || e != edges[j-1] {
+			edges[j] = e
+			j++
+		}
+	}
+	return edges[:j]
+}
+
+func (a *goGraph) cmp(b *goGraph) int {
+	if len(a.nodes) != len(b.nodes) {
+		return cmpInt(len(a.nodes), len(b.nodes))
+	}
+	for i := range a.nodes {
+		if a.nodes[i] != b.nodes[i] {
+			return cmpInt(int(a.nodes[i]), int(b.nodes[i]))
+		}
+	}
+	ae := a.sortedEdges()
+	be := b.sortedEdges()
+	if len(ae) != len(be) {
+		return cmpInt(len(ae), len(be))
+	}
+	for i := range ae {
+		if ae[i].Src != be[i].Src {
+			return cmpInt(int(ae[i].Src), int(be[i].Src))
+		}
+		if ae[i].Targ != be[i].Targ {
+			return cmpInt(int(ae[i].Targ), int(be[i].Targ))
+		}
+	}
+	return 0
+}
+
+func cmpInt(a, b int) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+func (g *goGraph) canonicalPermutation() (mapping []uint, stats *Stats) {
+	if len(g.nodes) == 0 {
+		return []uint{}, nil
+	}
+	mapping, _, stats, _ = searchCanonical(g.nodes, g.edges, g.directed, withoutBudgets(g.opts), nil)
+	return mapping, stats
+}
+
+func (g *goGraph) canonical() (*goGraph, *Stats) {
+	mapping, stats := g.canonicalPermutation()
+	can := &goGraph{
+		nodes:    make([]uint32, len(g.nodes)),
+		edges:    make([]BlissEdge, 0, len(g.edges)),
+		directed: g.directed,
+		opts:     g.opts,
+	}
+	for v, color := range g.nodes {
+		can.nodes[mapping[v]] = color
+	}
+	for _, e := range g.edges {
+		can.addEdge(mapping[e.Src], mapping[e.Targ])
+	}
+	return can, stats
+}
+
+func (g *goGraph) automorphisms() (generators [][]uint) {
+	return automorphisms(g.nodes, g.edges, g.directed, g.opts)
+}
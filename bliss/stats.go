@@ -0,0 +1,47 @@
+package bliss
+
+/*
+  Copyright (c) 2016 Tim Henderson
+  Released under the GNU General Public License version 3.
+
+  This file is part of goiso a wrapper around bliss.
+
+  bliss is free software: you can redistribute it and/or modify
+  it under the terms of the GNU General Public License version 3
+  as published by the Free Software Foundation.
+
+  bliss is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU General Public License for more details.
+
+  You should have received a copy of the GNU General Public License
+  along with goiso.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Statistics gathered by bliss while searching for the canonical labeling
+// (or the automorphisms) of a graph. Useful for finding out which graphs make
+// the search hard.
+type Stats struct {
+	GroupSize       *big.Int // The size of the automorphism group
+	GroupSizeApprox float64  // A floating point approximation of GroupSize
+	Nodes           uint64   // The number of nodes in the search tree
+	LeafNodes       uint64   // The number of leaf nodes in the search tree
+	BadNodes        uint64   // The number of bad nodes in the search tree
+	CanonUpdates    uint64   // The number of canonical representative updates
+	Generators      uint64   // The number of generators found
+	MaxLevel        uint64   // The maximal depth of the search tree
+}
+
+// Formats the stats on a single line. Handy for logging.
+func (s *Stats) String() string {
+	return fmt.Sprintf(
+		"nodes=%v leaf-nodes=%v bad-nodes=%v canrep-updates=%v generators=%v max-level=%v |Aut|=%v",
+		s.Nodes, s.LeafNodes, s.BadNodes, s.CanonUpdates, s.Generators, s.MaxLevel, s.GroupSize,
+	)
+}
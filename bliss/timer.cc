@@ -1,3 +1,5 @@
+//go:build cgo && !purego
+
 #include <unistd.h>
 #include <sys/times.h>
 #include "timer.hh"
@@ -1,3 +1,5 @@
+//go:build cgo && !purego
+
 #include <cassert>
 #include <vector>
 #include "utils.hh"
@@ -0,0 +1,163 @@
+package goiso
+
+/*
+  Copyright (c) 2014 Tim Henderson
+  Released under the GNU General Public License version 3.
+
+  This file is part of goiso a wrapper around bliss.
+
+  bliss is free software: you can redistribute it and/or modify
+  it under the terms of the GNU General Public License version 3
+  as published by the Free Software Foundation.
+
+  bliss is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU General Public License for more details.
+
+  You should have received a copy of the GNU General Public License
+  along with goiso.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+)
+
+import (
+	"github.com/timtadh/goiso/bliss"
+)
+
+// Computes the canonical permutation of a labeled graph given by its
+// vertices and edges. A Graph uses its Canonicalizer for CanonicalPermutation,
+// Canonical and every SubGraph extracted from it. The vertices are indexed
+// by position (the Src and Targ of the edges refer to the positions in V).
+// Read the returned variables as:
+//
+//   - Vord [original-index] -> new-index of vertices
+//   - Eord [original-index] -> new-index of edges
+//   - canonized is true if the graph was already in canonical order
+//     and false otherwise
+//
+// Canonical forms computed by different Canonicalizers are not comparable.
+type Canonicalizer interface {
+	CanonicalPermutation(V Vertices, E Edges) (Vord, Eord []int, canonized bool)
+}
+
+// A Canonicalizer which can canonize many graphs at once more cheaply than
+// one at a time. Graph.SubGraphBatch uses it when available. Vords[i],
+// Eords[i] and canonized[i] are the CanonicalPermutation of Vs[i] and Es[i].
+type BatchCanonicalizer interface {
+	Canonicalizer
+	CanonicalPermutations(Vs []Vertices, Es []Edges) (Vords, Eords [][]int, canonized []bool)
+}
+
+// A Canonicalizer whose search can be abandoned. Graph.SubGraphContext uses
+// it when available. The opts carry the search budgets (see bliss.Options)
+// and may be nil. When the search is abandoned err is a *bliss.AbortError.
+type ContextCanonicalizer interface {
+	Canonicalizer
+	CanonicalPermutationContext(ctx context.Context, V Vertices, E Edges, opts *bliss.Options) (Vord, Eord []int, canonized bool, err error)
+}
+
+// Adapts a plain function into a Canonicalizer. Handy for wrapping another
+// Canonicalizer (for caching or instrumentation).
+type CanonicalizerFunc func(V Vertices, E Edges) (Vord, Eord []int, canonized bool)
+
+func (f CanonicalizerFunc) CanonicalPermutation(V Vertices, E Edges) (Vord, Eord []int, canonized bool) {
+	return f(V, E)
+}
+
+// Canonicalizes with bliss (see bliss.Map). Options tunes the search, if it
+// is nil the bliss defaults are used. The zero value is the Canonicalizer
+// graphs use by default.
+type BlissCanonicalizer struct {
+	Options *bliss.Options
+}
+
+func (b *BlissCanonicalizer) CanonicalPermutation(V Vertices, E Edges) (Vord, Eord []int, canonized bool) {
+	bMap := bliss.NewMap(len(V), len(E), V.Iterate(), E.Iterate())
+	if b.Options == nil {
+		return bMap.CanonicalPermutation()
+	}
+	Vord, Eord, canonized, _ = bMap.CanonicalPermutationOpts(b.Options)
+	return Vord, Eord, canonized
+}
+
+// Canonicalizes all of the graphs with a single call into bliss. See
+// bliss.CanonicalPermutationBatch.
+func (b *BlissCanonicalizer) CanonicalPermutations(Vs []Vertices, Es []Edges) (Vords, Eords [][]int, canonized []bool) {
+	maps := make([]*bliss.Map, 0, len(Vs))
+	for i := range Vs {
+		maps = append(maps, bliss.NewMap(len(Vs[i]), len(Es[i]), Vs[i].Iterate(), Es[i].Iterate()))
+	}
+	return bliss.CanonicalPermutationBatchOpts(maps, b.Options)
+}
+
+// Canonicalizes with bliss, stopping early if the context is canceled or the
+// budgets run out. Only the budgets (MaxNodes, Timeout) are taken from opts,
+// the search is tuned by the Canonicalizer's Options so the result is
+// comparable with CanonicalPermutation. opts may be nil. See
+// bliss.Map.CanonicalPermutationContext.
+func (b *BlissCanonicalizer) CanonicalPermutationContext(ctx context.Context, V Vertices, E Edges, opts *bliss.Options) (Vord, Eord []int, canonized bool, err error) {
+	if opts != nil {
+		var o bliss.Options
+		if b.Options != nil {
+			o = *b.Options
+		} else {
+			o = *bliss.DefaultOptions()
+		}
+		o.MaxNodes = opts.MaxNodes
+		o.Timeout = opts.Timeout
+		opts = &o
+	} else {
+		opts = b.Options
+	}
+	bMap := bliss.NewMap(len(V), len(E), V.Iterate(), E.Iterate())
+	Vord, Eord, canonized, _, err = bMap.CanonicalPermutationContext(ctx, opts)
+	return Vord, Eord, canonized, err
+}
+
+var defaultCanonicalizer = &BlissCanonicalizer{}
+
+// Set the Canonicalizer used by the graph and the subgraphs extracted from
+// it. A nil c restores the default (bliss with its default options). Set it
+// before extracting any subgraphs: subgraphs canonized by different
+// Canonicalizers can not be compared.
+func (g *Graph) SetCanonicalizer(c Canonicalizer) {
+	g.canonicalizer = c
+}
+
+// The Canonicalizer used by the graph.
+func (g *Graph) Canonicalizer() Canonicalizer {
+	if g.canonicalizer == nil {
+		return defaultCanonicalizer
+	}
+	return g.canonicalizer
+}
+
+// Canonicalizes V and E (the graph or one of its subgraphs) with the graph's
+// Canonicalizer. In stable label mode the colors are normalized first.
+func (g *Graph) canonicalPermutation(V Vertices, E Edges) (Vord, Eord []int, canonized bool) {
+	if g.stable {
+		V, E = g.stableColors(V, E)
+	}
+	return g.Canonicalizer().CanonicalPermutation(V, E)
+}
+
+// The same as canonicalPermutation but the search may be abandoned. If the
+// graph's Canonicalizer is not a ContextCanonicalizer the context is only
+// checked before the search starts and the opts are ignored.
+func (g *Graph) canonicalPermutationContext(ctx context.Context, V Vertices, E Edges, opts *bliss.Options) (Vord, Eord []int, canonized bool, err error) {
+	if g.stable {
+		V, E = g.stableColors(V, E)
+	}
+	c := g.Canonicalizer()
+	if cc, ok := c.(ContextCanonicalizer); ok {
+		return cc.CanonicalPermutationContext(ctx, V, E, opts)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, false, &bliss.AbortError{Reason: bliss.Canceled, Err: err}
+	}
+	Vord, Eord, canonized = c.CanonicalPermutation(V, E)
+	return Vord, Eord, canonized, nil
+}
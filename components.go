@@ -0,0 +1,162 @@
+package goiso
+
+/*
+  Copyright (c) 2014 Tim Henderson
+  Released under the GNU General Public License version 3.
+
+  This file is part of goiso a wrapper around bliss.
+
+  bliss is free software: you can redistribute it and/or modify
+  it under the terms of the GNU General Public License version 3
+  as published by the Free Software Foundation.
+
+  bliss is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU General Public License for more details.
+
+  You should have received a copy of the GNU General Public License
+  along with goiso.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+)
+
+// Splits the graph into its weakly connected components (edge directions
+// are ignored). Each component is returned as a canonical SubGraph. The
+// components are ordered by their smallest vertex Idx. canonized[i] is read
+// as the canonized result of SubGraphBatch for sgs[i].
+func (g *Graph) Components() (sgs []*SubGraph, canonized []bool) {
+	comp := make([]int, len(g.V))
+	for i := range comp {
+		comp[i] = -1
+	}
+	var vidSets [][]int
+	for root := range g.V {
+		if comp[root] >= 0 {
+			continue
+		}
+		c := len(vidSets)
+		vids := []int{root}
+		comp[root] = c
+		for i := 0; i < len(vids); i++ {
+			u := vids[i]
+			for _, e := range g.Kids[u] {
+				if comp[e.Targ] < 0 {
+					comp[e.Targ] = c
+					vids = append(vids, e.Targ)
+				}
+			}
+			for _, e := range g.Parents[u] {
+				if comp[e.Src] < 0 {
+					comp[e.Src] = c
+					vids = append(vids, e.Src)
+				}
+			}
+		}
+		vidSets = append(vidSets, vids)
+	}
+	return g.SubGraphBatch(vidSets, nil)
+}
+
+// Splits the graph into its strongly connected components. Edges between
+// components are not part of any component. The components are ordered by
+// their smallest vertex Idx. canonized[i] is read as the canonized result of
+// SubGraphBatch for sgs[i].
+func (g *Graph) StrongComponents() (sgs []*SubGraph, canonized []bool) {
+	// Tarjan's algorithm with an explicit stack
+	const unvisited = -1
+	index := make([]int, len(g.V))
+	low := make([]int, len(g.V))
+	onStack := make([]bool, len(g.V))
+	for i := range index {
+		index[i] = unvisited
+	}
+	type frame struct {
+		v, kid int
+	}
+	var stack []int
+	var vidSets [][]int
+	next := 0
+	for root := range g.V {
+		if index[root] != unvisited {
+			continue
+		}
+		calls := []frame{{root, 0}}
+		index[root], low[root] = next, next
+		next++
+		stack = append(stack, root)
+		onStack[root] = true
+		for len(calls) > 0 {
+			f := &calls[len(calls)-1]
+			if f.kid < len(g.Kids[f.v]) {
+				w := g.Kids[f.v][f.kid].Targ
+				f.kid++
+				if index[w] == unvisited {
+					index[w], low[w] = next, next
+					next++
+					stack = append(stack, w)
+					onStack[w] = true
+					calls = append(calls, frame{w, 0})
+				} else if onStack[w] && index[w] < low[f.v] {
+					low[f.v] = index[w]
+				}
+				continue
+			}
+			v := f.v
+			calls = calls[:len(calls)-1]
+			if len(calls) > 0 {
+				if p := calls[len(calls)-1].v; low[v] < low[p] {
+					low[p] = low[v]
+				}
+			}
+			if low[v] == index[v] {
+				var vids []int
+				for {
+					w := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+					onStack[w] = false
+					vids = append(vids, w)
+					if w == v {
+						break
+					}
+				}
+				sort.Ints(vids)
+				vidSets = append(vidSets, vids)
+			}
+		}
+	}
+	sort.Slice(vidSets, func(i, j int) bool {
+		return vidSets[i][0] < vidSets[j][0]
+	})
+	return g.SubGraphBatch(vidSets, nil)
+}
+
+// A canonical label for the whole graph built from the ShortLabels of its
+// weakly connected components. With a Canonicalizer computing true canonical
+// forms (such as the default) two graphs have the same ComponentLabel if and
+// only if they have the same multiset of components, that is if they are
+// isomorphic. Each component is canonized on its own which is much cheaper
+// than canonizing a large disconnected graph in one go. Like ShortLabel it
+// depends on the colors so compare graphs sharing a GraphDB or in stable
+// label mode. Note the label is not comparable with ShortLabel.
+func (g *Graph) ComponentLabel() []byte {
+	components, _ := g.Components()
+	labels := make([][]byte, len(components))
+	for i, sg := range components {
+		labels[i] = sg.ShortLabel()
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return bytes.Compare(labels[i], labels[j]) < 0
+	})
+	label := make([]byte, 0, 4+8*len(labels))
+	label = binary.BigEndian.AppendUint32(label, uint32(len(labels)))
+	for _, l := range labels {
+		label = binary.BigEndian.AppendUint32(label, uint32(len(l)))
+		label = append(label, l...)
+	}
+	return label
+}
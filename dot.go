@@ -0,0 +1,576 @@
+package goiso
+
+/*
+  Copyright (c) 2014 Tim Henderson
+  Released under the GNU General Public License version 3.
+
+  This file is part of goiso a wrapper around bliss.
+
+  bliss is free software: you can redistribute it and/or modify
+  it under the terms of the GNU General Public License version 3
+  as published by the Free Software Foundation.
+
+  bliss is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU General Public License for more details.
+
+  You should have received a copy of the GNU General Public License
+  along with goiso.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Reads a graph in the graphviz dot language such as the output of
+// Graph.String and SubGraph.StringWithAttrs. Each node becomes a vertex, in
+// the order the nodes first appear, and each edge an edge. The label
+// attribute is the label (nodes and edges without one get the empty label).
+// Nodes named by an integer keep it as their Id, others get their Idx. Use
+// LoadDotWithAttrs to keep the other attributes.
+//
+// Both digraphs and (undirected) graphs are read. An undirected edge becomes
+// an edge in each direction. Subgraphs group statements but edges to a
+// subgraph are not supported. Ports are ignored.
+func LoadDot(r io.Reader) (*Graph, error) {
+	g, _, _, err := LoadDotWithAttrs(r)
+	return g, err
+}
+
+// The same as LoadDot but also returns the other attributes of the vertices
+// (keyed by vertex Idx) and of the edges (keyed by edge Idx). The values are
+// strings. Nodes whose name is not an integer have it in the "name"
+// attribute. The "\n[line: N]" StringWithAttrs appends to the labels of
+// vertices with a start_line attribute is removed.
+func LoadDotWithAttrs(r io.Reader) (g *Graph, vattrs, eattrs map[int]map[string]interface{}, err error) {
+	p := &dotParser{
+		lex:   newDotLexer(r),
+		nodes: make(map[string]int),
+	}
+	defer func() {
+		if e := recover(); e != nil {
+			if de, ok := e.(dotError); ok {
+				g, vattrs, eattrs, err = nil, nil, nil, de
+				return
+			}
+			panic(e)
+		}
+	}()
+	p.graph()
+	g, vattrs, eattrs = p.build()
+	return g, vattrs, eattrs, nil
+}
+
+// Escapes a string for a double quoted dot ID. Only backslashes, quotes and
+// newlines need escaping. LoadDot reverses it.
+func dotEscape(s string) string {
+	s = strings.Replace(s, "\\", "\\\\", -1)
+	s = strings.Replace(s, "\n", "\\n", -1)
+	s = strings.Replace(s, "\"", "\\\"", -1)
+	return s
+}
+
+type dotError struct {
+	error
+}
+
+type dotToken struct {
+	kind rune // 'i' for IDs otherwise the punctuation ('-' for both edge ops)
+	text string
+	line int
+}
+
+type dotLexer struct {
+	r    *bufio.Reader
+	line int
+	peek *dotToken
+}
+
+func newDotLexer(r io.Reader) *dotLexer {
+	return &dotLexer{r: bufio.NewReader(r), line: 1}
+}
+
+func (l *dotLexer) errorf(line int, format string, args ...interface{}) {
+	panic(dotError{fmt.Errorf("goiso: DOT: error in line %v: %v", line, fmt.Sprintf(format, args...))})
+}
+
+func (l *dotLexer) read() (rune, bool) {
+	c, _, err := l.r.ReadRune()
+	if err == io.EOF {
+		return 0, false
+	} else if err != nil {
+		panic(dotError{err})
+	}
+	if c == '\n' {
+		l.line++
+	}
+	return c, true
+}
+
+func (l *dotLexer) unread(c rune) {
+	l.r.UnreadRune()
+	if c == '\n' {
+		l.line--
+	}
+}
+
+// Returns the next token without consuming it. kind is 0 at the end.
+func (l *dotLexer) Peek() *dotToken {
+	if l.peek == nil {
+		l.peek = l.lex()
+	}
+	return l.peek
+}
+
+func (l *dotLexer) Next() *dotToken {
+	t := l.Peek()
+	l.peek = nil
+	return t
+}
+
+func (l *dotLexer) lex() *dotToken {
+	atLineStart := l.line == 1
+	for {
+		c, ok := l.read()
+		if !ok {
+			return &dotToken{line: l.line}
+		}
+		switch {
+		case c == '\n':
+			atLineStart = true
+			continue
+		case unicode.IsSpace(c):
+			continue
+		case c == '#' && atLineStart:
+			// preprocessor output lines
+			l.skipLine()
+			continue
+		case c == '/':
+			if n, ok := l.read(); ok && n == '/' {
+				l.skipLine()
+				atLineStart = true
+				continue
+			} else if ok && n == '*' {
+				l.skipComment()
+				continue
+			}
+			l.errorf(l.line, "unexpected /")
+		}
+		line := l.line
+		switch {
+		case strings.ContainsRune("{}[]=;,:", c):
+			return &dotToken{kind: c, line: line}
+		case c == '-':
+			n, ok := l.read()
+			if ok && (n == '>' || n == '-') {
+				return &dotToken{kind: '-', text: string([]rune{c, n}), line: line}
+			}
+			if ok {
+				l.unread(n)
+			}
+			return &dotToken{kind: 'i', text: "-" + l.numeral(), line: line}
+		case c == '"':
+			return &dotToken{kind: 'i', text: l.quoted(line), line: line}
+		case c == '<':
+			return &dotToken{kind: 'i', text: l.html(line), line: line}
+		case c == '.' || unicode.IsDigit(c):
+			l.unread(c)
+			return &dotToken{kind: 'i', text: l.numeral(), line: line}
+		case c == '_' || unicode.IsLetter(c):
+			id := []rune{c}
+			for {
+				n, ok := l.read()
+				if !ok {
+					break
+				}
+				if n != '_' && !unicode.IsLetter(n) && !unicode.IsDigit(n) {
+					l.unread(n)
+					break
+				}
+				id = append(id, n)
+			}
+			return &dotToken{kind: 'i', text: string(id), line: line}
+		}
+		l.errorf(line, "unexpected %q", c)
+	}
+}
+
+func (l *dotLexer) skipLine() {
+	for {
+		c, ok := l.read()
+		if !ok || c == '\n' {
+			return
+		}
+	}
+}
+
+func (l *dotLexer) skipComment() {
+	line := l.line
+	star := false
+	for {
+		c, ok := l.read()
+		if !ok {
+			l.errorf(line, "unterminated comment")
+		}
+		if star && c == '/' {
+			return
+		}
+		star = c == '*'
+	}
+}
+
+func (l *dotLexer) numeral() string {
+	var num []rune
+	for {
+		c, ok := l.read()
+		if !ok {
+			break
+		}
+		if c != '.' && !unicode.IsDigit(c) {
+			l.unread(c)
+			break
+		}
+		num = append(num, c)
+	}
+	if len(num) == 0 {
+		l.errorf(l.line, "expected a number")
+	}
+	return string(num)
+}
+
+// Reads a double quoted string (the opening quote has been read) and any
+// strings concatenated to it with +.
+func (l *dotLexer) quoted(line int) string {
+	var s []rune
+	for {
+		c, ok := l.read()
+		if !ok {
+			l.errorf(line, "unterminated string")
+		}
+		if c == '"' {
+			break
+		}
+		if c == '\\' {
+			n, ok := l.read()
+			if !ok {
+				l.errorf(line, "unterminated string")
+			}
+			switch n {
+			case '"', '\\':
+				s = append(s, n)
+			case 'n':
+				s = append(s, '\n')
+			case '\n':
+				// a line continuation
+			default:
+				s = append(s, c, n)
+			}
+			continue
+		}
+		s = append(s, c)
+	}
+	// "a" + "b"
+	for {
+		c, ok := l.read()
+		if !ok {
+			return string(s)
+		}
+		if c == '+' {
+			for {
+				c, ok = l.read()
+				if !ok || !unicode.IsSpace(c) {
+					break
+				}
+			}
+			if c != '"' {
+				l.errorf(l.line, "expected a string after +")
+			}
+			return string(s) + l.quoted(l.line)
+		}
+		if !unicode.IsSpace(c) {
+			l.unread(c)
+			return string(s)
+		}
+	}
+}
+
+// Reads an HTML string (the opening < has been read). The result keeps the
+// inner brackets.
+func (l *dotLexer) html(line int) string {
+	var s []rune
+	depth := 1
+	for {
+		c, ok := l.read()
+		if !ok {
+			l.errorf(line, "unterminated HTML string")
+		}
+		if c == '<' {
+			depth++
+		} else if c == '>' {
+			depth--
+			if depth == 0 {
+				return string(s)
+			}
+		}
+		s = append(s, c)
+	}
+}
+
+type dotNode struct {
+	name  string
+	attrs map[string]string
+}
+
+type dotEdge struct {
+	src, targ  int
+	undirected bool
+	attrs      map[string]string
+}
+
+type dotParser struct {
+	lex      *dotLexer
+	directed bool
+	names    []dotNode
+	nodes    map[string]int
+	edges    []dotEdge
+}
+
+func (p *dotParser) expect(kind rune) *dotToken {
+	t := p.lex.Next()
+	if t.kind != kind {
+		p.lex.errorf(t.line, "expected %q got %v", kind, t)
+	}
+	return t
+}
+
+func (t *dotToken) String() string {
+	switch t.kind {
+	case 0:
+		return "the end of the input"
+	case 'i', '-':
+		return strconv.Quote(t.text)
+	}
+	return strconv.QuoteRune(t.kind)
+}
+
+func (t *dotToken) is(keyword string) bool {
+	return t.kind == 'i' && strings.EqualFold(t.text, keyword)
+}
+
+// graph : [ strict ] (graph | digraph) [ ID ] '{' stmt_list '}'
+func (p *dotParser) graph() {
+	t := p.lex.Next()
+	if t.is("strict") {
+		t = p.lex.Next()
+	}
+	switch {
+	case t.is("digraph"):
+		p.directed = true
+	case t.is("graph"):
+	default:
+		p.lex.errorf(t.line, "expected digraph or graph got %v", t)
+	}
+	if p.lex.Peek().kind == 'i' {
+		p.lex.Next()
+	}
+	p.expect('{')
+	p.stmts(make(map[string]string), make(map[string]string))
+	p.expect('}')
+	if t := p.lex.Next(); t.kind != 0 {
+		p.lex.errorf(t.line, "expected the end of the input got %v", t)
+	}
+}
+
+// Parses statements up to a closing brace. nodeDefs and edgeDefs are the
+// attributes set by node and edge statements in this scope.
+func (p *dotParser) stmts(nodeDefs, edgeDefs map[string]string) {
+	for {
+		t := p.lex.Peek()
+		switch {
+		case t.kind == '}' || t.kind == 0:
+			return
+		case t.kind == ';':
+			p.lex.Next()
+		case t.kind == '{' || t.is("subgraph"):
+			p.subgraph(nodeDefs, edgeDefs)
+			if p.lex.Peek().kind == '-' {
+				p.lex.errorf(p.lex.Peek().line, "edges to subgraphs are not supported")
+			}
+		case t.is("graph"):
+			p.lex.Next()
+			p.attrList()
+		case t.is("node"):
+			p.lex.Next()
+			for k, v := range p.attrList() {
+				nodeDefs[k] = v
+			}
+		case t.is("edge"):
+			p.lex.Next()
+			for k, v := range p.attrList() {
+				edgeDefs[k] = v
+			}
+		case t.kind == 'i':
+			p.lex.Next()
+			if p.lex.Peek().kind == '=' {
+				// a graph attribute
+				p.lex.Next()
+				p.expect('i')
+				continue
+			}
+			p.port()
+			if p.lex.Peek().kind == '-' {
+				p.edgeStmt(t, nodeDefs, edgeDefs)
+			} else {
+				n := p.node(t.text, nodeDefs)
+				for k, v := range p.attrList() {
+					p.names[n].attrs[k] = v
+				}
+			}
+		default:
+			p.lex.errorf(t.line, "unexpected %v", t)
+		}
+	}
+}
+
+func (p *dotParser) subgraph(nodeDefs, edgeDefs map[string]string) {
+	if p.lex.Next().is("subgraph") {
+		if p.lex.Peek().kind == 'i' {
+			p.lex.Next()
+		}
+		p.expect('{')
+	}
+	copyDefs := func(defs map[string]string) map[string]string {
+		c := make(map[string]string, len(defs))
+		for k, v := range defs {
+			c[k] = v
+		}
+		return c
+	}
+	p.stmts(copyDefs(nodeDefs), copyDefs(edgeDefs))
+	p.expect('}')
+}
+
+// Skips a port: ':' ID [ ':' ID ]
+func (p *dotParser) port() {
+	for i := 0; i < 2 && p.lex.Peek().kind == ':'; i++ {
+		p.lex.Next()
+		p.expect('i')
+	}
+}
+
+// edge_stmt : node_id edgeRHS [ attr_list ] where the first node has been
+// read.
+func (p *dotParser) edgeStmt(first *dotToken, nodeDefs, edgeDefs map[string]string) {
+	ends := []int{p.node(first.text, nodeDefs)}
+	var ops []*dotToken
+	for p.lex.Peek().kind == '-' {
+		op := p.lex.Next()
+		if (op.text == "->") != p.directed {
+			p.lex.errorf(op.line, "%v in a %v", op.text, map[bool]string{true: "digraph", false: "graph"}[p.directed])
+		}
+		t := p.lex.Peek()
+		if t.kind == '{' || t.is("subgraph") {
+			p.lex.errorf(t.line, "edges to subgraphs are not supported")
+		}
+		t = p.expect('i')
+		p.port()
+		ops = append(ops, op)
+		ends = append(ends, p.node(t.text, nodeDefs))
+	}
+	attrs := p.attrList()
+	for i := range ops {
+		e := dotEdge{src: ends[i], targ: ends[i+1], undirected: !p.directed, attrs: make(map[string]string)}
+		for k, v := range edgeDefs {
+			e.attrs[k] = v
+		}
+		for k, v := range attrs {
+			e.attrs[k] = v
+		}
+		p.edges = append(p.edges, e)
+	}
+}
+
+// The index of the named node, adding it if needed.
+func (p *dotParser) node(name string, nodeDefs map[string]string) int {
+	if n, has := p.nodes[name]; has {
+		return n
+	}
+	n := dotNode{name: name, attrs: make(map[string]string)}
+	for k, v := range nodeDefs {
+		n.attrs[k] = v
+	}
+	p.nodes[name] = len(p.names)
+	p.names = append(p.names, n)
+	return len(p.names) - 1
+}
+
+// attr_list : '[' [ a_list ] ']' [ attr_list ]
+func (p *dotParser) attrList() map[string]string {
+	attrs := make(map[string]string)
+	for p.lex.Peek().kind == '[' {
+		p.lex.Next()
+		for p.lex.Peek().kind != ']' {
+			k := p.expect('i')
+			p.expect('=')
+			v := p.expect('i')
+			attrs[k.text] = v.text
+			if t := p.lex.Peek(); t.kind == ',' || t.kind == ';' {
+				p.lex.Next()
+			}
+		}
+		p.lex.Next()
+	}
+	return attrs
+}
+
+func (p *dotParser) build() (*Graph, map[int]map[string]interface{}, map[int]map[string]interface{}) {
+	g := NewGraph(len(p.names), len(p.edges))
+	vattrs := make(map[int]map[string]interface{})
+	eattrs := make(map[int]map[string]interface{})
+	for i, n := range p.names {
+		label := n.attrs["label"]
+		if line, has := n.attrs["start_line"]; has {
+			label = strings.TrimSuffix(label, "\n[line: "+line+"]")
+		}
+		id, err := strconv.Atoi(n.name)
+		if err != nil {
+			id = i
+			n.attrs["name"] = n.name
+		}
+		g.AddVertex(id, label)
+		for k, v := range n.attrs {
+			if k == "label" {
+				continue
+			}
+			if vattrs[i] == nil {
+				vattrs[i] = make(map[string]interface{})
+			}
+			vattrs[i][k] = v
+		}
+	}
+	add := func(src, targ int, attrs map[string]string) {
+		e := g.AddEdge(&g.V[src], &g.V[targ], attrs["label"])
+		for k, v := range attrs {
+			if k == "label" {
+				continue
+			}
+			if eattrs[e.Idx] == nil {
+				eattrs[e.Idx] = make(map[string]interface{})
+			}
+			eattrs[e.Idx][k] = v
+		}
+	}
+	for _, e := range p.edges {
+		add(e.src, e.targ, e.attrs)
+		if e.undirected && e.src != e.targ {
+			add(e.targ, e.src, e.attrs)
+		}
+	}
+	return &g, vattrs, eattrs
+}
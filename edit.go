@@ -0,0 +1,194 @@
+package goiso
+
+/*
+  Copyright (c) 2014 Tim Henderson
+  Released under the GNU General Public License version 3.
+
+  This file is part of goiso a wrapper around bliss.
+
+  bliss is free software: you can redistribute it and/or modify
+  it under the terms of the GNU General Public License version 3
+  as published by the Free Software Foundation.
+
+  bliss is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU General Public License for more details.
+
+  You should have received a copy of the GNU General Public License
+  along with goiso.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Reopens a finalized graph so vertices and edges can again be added,
+// removed and relabeled. The graph is finalized again on demand by the
+// methods which need bliss.
+func (g *Graph) Unfinalize() {
+	g.closed = false
+	g.canon = false
+	g.blissMap = nil
+	if len(g.colorFreq) < len(g.colors()) {
+		g.countColors()
+	}
+}
+
+// A deep copy of the graph which is not finalized. The copy shares the
+// Canonicalizer, stable label mode and GraphDB (if any) of the graph.
+func (g *Graph) Clone() Graph {
+	ng := Graph{
+		V:         make([]Vertex, len(g.V)),
+		E:         make([]Edge, len(g.E)),
+		Colors:    make([]string, len(g.colors())),
+		Labels:    make(map[string]int, len(g.Labels)),
+		colorFreq: make([]int, len(g.colorFreq)),
+	}
+	ng.canonicalizer = g.canonicalizer
+	ng.stable = g.stable
+	ng.db = g.db
+	copy(ng.V, g.V)
+	copy(ng.E, g.E)
+	copy(ng.colorFreq, g.colorFreq)
+	if g.db != nil {
+		ng.Colors = g.db.Colors
+		ng.Labels = g.db.Labels
+	} else {
+		copy(ng.Colors, g.Colors)
+		for label, cid := range g.Labels {
+			ng.Labels[label] = cid
+		}
+	}
+	if len(ng.colorFreq) < len(ng.Colors) {
+		ng.countColors()
+	}
+	ng.rebuildAdjacency()
+	return ng
+}
+
+// Removes the edge at idx. The edges after it move down one place. Read the
+// returned mapping as:
+//
+//     Eord[old edge Idx] -> new edge Idx or -1 if removed
+//
+// Like AddEdge this does nothing (and returns nil) once the graph is
+// finalized, see Unfinalize.
+func (g *Graph) RemoveEdge(idx int) (Eord []int) {
+	if g.closed {
+		return nil
+	}
+	_, Eord = g.remove(-1, func(e *Edge) bool { return e.Idx == idx })
+	return Eord
+}
+
+// Removes the vertex at idx along with its edges. The indices of the
+// remaining vertices and edges are compacted. Read the returned mappings as:
+//
+//     Vord[old vertex Idx] -> new vertex Idx or -1 if removed
+//     Eord[old edge Idx] -> new edge Idx or -1 if removed
+//
+// Like AddVertex this does nothing (and returns nil) once the graph is
+// finalized, see Unfinalize.
+func (g *Graph) RemoveVertex(idx int) (Vord, Eord []int) {
+	if g.closed {
+		return nil, nil
+	}
+	return g.remove(idx, func(e *Edge) bool { return e.Src == idx || e.Targ == idx })
+}
+
+// Changes the label of the vertex at idx. Returns the updated vertex or nil
+// if the graph is finalized.
+func (g *Graph) Relabel(idx int, label string) *Vertex {
+	if g.closed {
+		return nil
+	}
+	g.colorFreq[g.V[idx].Color] -= 1
+	g.V[idx].Color = g.AddColor(label)
+	v := g.V[idx]
+	return &v
+}
+
+// Changes the label of the edge at idx. Returns the updated edge or nil if
+// the graph is finalized.
+func (g *Graph) RelabelEdge(idx int, label string) *Edge {
+	if g.closed {
+		return nil
+	}
+	g.colorFreq[g.E[idx].Color] -= 1
+	g.E[idx].Color = g.AddColor(label)
+	for _, e := range g.Kids[g.E[idx].Src] {
+		if e.Idx == idx {
+			e.Color = g.E[idx].Color
+		}
+	}
+	for _, e := range g.Parents[g.E[idx].Targ] {
+		if e.Idx == idx {
+			e.Color = g.E[idx].Color
+		}
+	}
+	e := g.E[idx]
+	return &e
+}
+
+// Removes the vertex vidx (if not -1) and the edges for which drop is true.
+func (g *Graph) remove(vidx int, drop func(*Edge) bool) (Vord, Eord []int) {
+	Vord = make([]int, len(g.V))
+	V := g.V[:0]
+	for i := range g.V {
+		if i == vidx {
+			g.colorFreq[g.V[i].Color] -= 1
+			Vord[i] = -1
+			continue
+		}
+		Vord[i] = len(V)
+		V = append(V, g.V[i].Copy(len(V)))
+	}
+	Eord = make([]int, len(g.E))
+	E := g.E[:0]
+	for i := range g.E {
+		if drop(&g.E[i]) {
+			g.colorFreq[g.E[i].Color] -= 1
+			Eord[i] = -1
+			continue
+		}
+		Eord[i] = len(E)
+		E = append(E, g.E[i].Copy(len(E), Vord[g.E[i].Src], Vord[g.E[i].Targ]))
+	}
+	g.V = V
+	g.E = E
+	g.rebuildAdjacency()
+	return Vord, Eord
+}
+
+// Rebuilds Kids and Parents from E. Like AddEdge each edge in them is a copy.
+// The copies share one allocation and each adjacency list is allocated at
+// its final size.
+func (g *Graph) rebuildAdjacency() {
+	outDeg := make([]int, len(g.V))
+	inDeg := make([]int, len(g.V))
+	for i := range g.E {
+		outDeg[g.E[i].Src]++
+		inDeg[g.E[i].Targ]++
+	}
+	g.Kids = make([][]*Edge, len(g.V))
+	g.Parents = make([][]*Edge, len(g.V))
+	for i := range g.V {
+		g.Kids[i] = make([]*Edge, 0, outDeg[i])
+		g.Parents[i] = make([]*Edge, 0, inDeg[i])
+	}
+	copies := make([]Edge, len(g.E))
+	copy(copies, g.E)
+	for i := range copies {
+		e := &copies[i]
+		g.Kids[e.Src] = append(g.Kids[e.Src], e)
+		g.Parents[e.Targ] = append(g.Parents[e.Targ], e)
+	}
+}
+
+// Recounts the frequency of every color from V and E.
+func (g *Graph) countColors() {
+	g.colorFreq = make([]int, len(g.colors()))
+	for i := range g.V {
+		g.colorFreq[g.V[i].Color] += 1
+	}
+	for i := range g.E {
+		g.colorFreq[g.E[i].Color] += 1
+	}
+}
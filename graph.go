@@ -20,6 +20,7 @@ package goiso
 */
 
 import (
+	"context"
 	"fmt"
 	"strings"
 )
@@ -39,6 +40,11 @@ type Graph struct {
 	closed    bool
 	canon     bool
 	blissMap  *bliss.Map
+	// nil means the default BlissCanonicalizer
+	canonicalizer Canonicalizer
+	stable        bool
+	// non nil if the labels are shared through a GraphDB
+	db *GraphDB
 }
 
 type Vertices []Vertex
@@ -142,6 +148,34 @@ func (g *Graph) SubGraph(vids []int, filtered_edges map[string]bool) (sg *SubGra
 	return canonSubGraph(g, V, E)
 }
 
+// The same as SubGraph but the canonicalization stops early with an error
+// (a *bliss.AbortError) if the context is canceled or the search budgets in
+// opts run out. opts may be nil. Use this when a single pathological subgraph
+// must not stall the whole computation. The graph's Canonicalizer is used; if
+// it is not a ContextCanonicalizer the search can not be interrupted once it
+// has started.
+func (g *Graph) SubGraphContext(ctx context.Context, vids []int, filtered_edges map[string]bool, opts *bliss.Options) (sg *SubGraph, canonized bool, err error) {
+	V := g.find_vertices(vids)
+	E := g.find_edges(vids, V, filtered_edges)
+	return canonSubGraphContext(ctx, g, V, E, opts)
+}
+
+// The same as SubGraph for each of the given sets of vertex ids. With the
+// default Canonicalizer all of the subgraphs are canonized with a single call
+// into bliss which saves most of the cgo overhead when extracting many small
+// subgraphs (see BatchCanonicalizer). sgs[i] and canonized[i] are the result
+// for vidSets[i].
+func (g *Graph) SubGraphBatch(vidSets [][]int, filtered_edges map[string]bool) (sgs []*SubGraph, canonized []bool) {
+	Vs := make([]Vertices, 0, len(vidSets))
+	Es := make([]Edges, 0, len(vidSets))
+	for _, vids := range vidSets {
+		V := g.find_vertices(vids)
+		Vs = append(Vs, V)
+		Es = append(Es, g.find_edges(vids, V, filtered_edges))
+	}
+	return canonSubGraphs(g, Vs, Es)
+}
+
 func (g *Graph) VertexSubGraph(vid int) (sg *SubGraph, canonized bool) {
 	V := g.find_vertices([]int{vid})
 	return canonSubGraph(g, V, []Edge{})
@@ -169,7 +203,7 @@ func (g *Graph) find_edges(vids []int, V []Vertex, filtered_edges map[string]boo
 	edges := make([]Edge, 0, len(vids))
 	for i, u := range vids {
 		for _, e := range g.Kids[u] {
-			if _, has := filtered_edges[g.Colors[e.Color]]; has {
+			if _, has := filtered_edges[g.colors()[e.Color]]; has {
 				continue
 			}
 			if j, has := vset[e.Targ]; has {
@@ -199,7 +233,7 @@ func (g *Graph) Label() string {
 		V = append(V, fmt.Sprintf(
 			"(%v:%v)",
 			v.Idx,
-			safe_label(g.Colors[v.Color]),
+			safe_label(g.colors()[v.Color]),
 		))
 	}
 	for _, e := range g.E {
@@ -207,7 +241,7 @@ func (g *Graph) Label() string {
 			"[%v->%v:%v]",
 			e.Src,
 			e.Targ,
-			safe_label(g.Colors[e.Color]),
+			safe_label(g.colors()[e.Color]),
 		))
 	}
 	return fmt.Sprintf("%d:%d%v%v", len(g.E), len(g.V), strings.Join(V, ""), strings.Join(E, ""))
@@ -222,7 +256,7 @@ func (g *Graph) String() string {
 		V = append(V, fmt.Sprintf(
 			"%v [label=\"%v\"];",
 			v.Id,
-			g.Colors[v.Color],
+			dotEscape(g.colors()[v.Color]),
 		))
 	}
 	for _, e := range g.E {
@@ -230,7 +264,7 @@ func (g *Graph) String() string {
 			"%v -> %v [label=\"%v\"];",
 			g.V[e.Src].Id,
 			g.V[e.Targ].Id,
-			g.Colors[e.Color],
+			dotEscape(g.colors()[e.Color]),
 		))
 	}
 	return fmt.Sprintf(
@@ -262,12 +296,17 @@ func (g *Graph) Canonical() (ng Graph, canonized bool) {
 		E:        make([]Edge, len(g.E)),
 		Kids:     make([][]*Edge, len(g.Kids)),
 		Parents:  make([][]*Edge, len(g.Parents)),
-		Colors:   make([]string, len(g.Colors)),
+		Colors:   make([]string, len(g.colors())),
 		Labels: make(map[string]int),
 		closed:   true,
 		canon:    true,
 	}
-	copy(ng.Colors, g.Colors)
+	ng.canonicalizer = g.canonicalizer
+	ng.stable = g.stable
+	ng.db = g.db
+	copy(ng.Colors, g.colors())
+	ng.colorFreq = make([]int, len(g.colorFreq))
+	copy(ng.colorFreq, g.colorFreq)
 	for cid, color := range ng.Colors {
 		ng.Labels[color] = cid
 	}
@@ -299,9 +338,27 @@ func (g *Graph) CanonicalPermutation() (Vord, Eord []int, canonized bool) {
 	if !g.closed {
 		g.Finalize()
 	}
+	if g.canonicalizer != nil || g.stable {
+		return g.canonicalPermutation(g.V, g.E)
+	}
 	return g.blissMap.CanonicalPermutation()
 }
 
+// Computes which vertices and edges are structurally equivalent. Two
+// vertices (edges) are in the same orbit if an automorphism of the labeled
+// graph maps one onto the other. Read the returned variables as:
+//
+//   - V[vertex.Idx] -> the smallest Idx of a vertex in the same orbit
+//   - E[edge.Idx] -> the smallest Idx of an edge in the same orbit
+//
+// Note: this method does finalize the graph as it calls into bliss.
+func (g *Graph) Orbits() (V, E []int) {
+	if !g.closed {
+		g.Finalize()
+	}
+	return g.blissMap.Orbits()
+}
+
 // Adds a vertex. The id is not used by this package but is preserved.
 // The purpose is for you to track the identity of each vertex. The
 // label is the label of the vertex.
@@ -358,6 +415,9 @@ func (g *Graph) ColorFrequency(color int) int {
 }
 
 func (g *Graph) AddColor(label string) int {
+	if g.db != nil {
+		return g.addSharedColor(label)
+	}
 	if cid, has := g.Labels[label]; has {
 		g.colorFreq[cid] += 1
 		return cid
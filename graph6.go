@@ -0,0 +1,156 @@
+package goiso
+
+/*
+  Copyright (c) 2014 Tim Henderson
+  Released under the GNU General Public License version 3.
+
+  This file is part of goiso a wrapper around bliss.
+
+  bliss is free software: you can redistribute it and/or modify
+  it under the terms of the GNU General Public License version 3
+  as published by the Free Software Foundation.
+
+  bliss is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU General Public License for more details.
+
+  You should have received a copy of the GNU General Public License
+  along with goiso.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+import (
+	"io"
+	"sort"
+)
+
+import (
+	"github.com/timtadh/goiso/bliss"
+)
+
+// Reads graphs in any of the graph6 family of formats (graph6, sparse6 and
+// digraph6, see bliss.DecodeGraph6) one per line. An undirected edge becomes
+// an edge in each direction. All vertices and edges are labeled with the
+// empty string and vertex i has Id i.
+func ReadGraph6(r io.Reader) ([]*Graph, error) {
+	var graphs []*Graph
+	err := bliss.ReadGraph6Lines(r, func(format bliss.Graph6Format, n int, edges []bliss.BlissEdge) error {
+		g := NewGraph(n, 2*len(edges))
+		for i := 0; i < n; i++ {
+			g.AddVertex(i, "")
+		}
+		for _, e := range edges {
+			g.AddEdge(&g.V[e.Src], &g.V[e.Targ], "")
+			if format != bliss.Digraph6 && e.Src != e.Targ {
+				g.AddEdge(&g.V[e.Targ], &g.V[e.Src], "")
+			}
+		}
+		graphs = append(graphs, &g)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return graphs, nil
+}
+
+// Writes the graph as one line in a format of the graph6 family. The labels
+// are dropped. For graph6 and sparse6 the direction of the edges is ignored
+// so an edge in each direction is written once. If canonical is true the
+// vertices are first put in the canonical order of the unlabeled graph
+// (counting the loops and parallel edges the format keeps): isomorphic
+// graphs are then written identically. Note the canonical order is bliss's
+// so the lines will not be byte for byte the same as nauty's labelg but they
+// identify the same isomorphism classes.
+func (g *Graph) WriteGraph6(w io.Writer, format bliss.Graph6Format, canonical bool) error {
+	edges := make([]bliss.BlissEdge, 0, len(g.E))
+	// the number of edges each way between each pair of vertices
+	counts := make(map[bliss.BlissEdge]*[2]int, len(g.E))
+	for i := range g.E {
+		e := bliss.BlissEdge{Src: uint32(g.E[i].Src), Targ: uint32(g.E[i].Targ)}
+		way := 0
+		if format != bliss.Digraph6 && e.Src > e.Targ {
+			e.Src, e.Targ = e.Targ, e.Src
+			way = 1
+		}
+		c, has := counts[e]
+		if !has {
+			c = new([2]int)
+			counts[e] = c
+		}
+		c[way]++
+		if format == bliss.Sparse6 {
+			// keep parallel edges: u -> v and v -> u make one edge
+			if c[way] > c[1-way] {
+				edges = append(edges, e)
+			}
+		} else if !has {
+			edges = append(edges, e)
+		}
+	}
+	if canonical {
+		if format == bliss.Graph6 {
+			// graph6 drops the loops so they must not change the order
+			simple := edges[:0]
+			for _, e := range edges {
+				if e.Src != e.Targ {
+					simple = append(simple, e)
+				}
+			}
+			edges = simple
+		}
+		edges = canonicalGraph6(len(g.V), edges, format == bliss.Digraph6)
+	}
+	_, err := w.Write(append(bliss.EncodeGraph6(format, len(g.V), edges), '\n'))
+	return err
+}
+
+// Relabels the edges by the canonical labeling of the unlabeled multigraph.
+// bliss only canonizes simple graphs so every distinct edge is subdivided by
+// a node colored with the edge's multiplicity and every vertex is colored
+// with its number of loops. Vertex colors are even and edge colors odd so
+// the two never mix.
+func canonicalGraph6(n int, edges []bliss.BlissEdge, directed bool) []bliss.BlissEdge {
+	nodes := make([]uint32, n, n+len(edges))
+	mult := make(map[bliss.BlissEdge]uint32, len(edges))
+	distinct := make([]bliss.BlissEdge, 0, len(edges))
+	for _, e := range edges {
+		if e.Src == e.Targ {
+			nodes[e.Src] += 2
+		} else {
+			if mult[e] == 0 {
+				distinct = append(distinct, e)
+			}
+			mult[e]++
+		}
+	}
+	subdivided := make([]bliss.BlissEdge, 0, 2*len(distinct))
+	for _, e := range distinct {
+		enode := uint32(len(nodes))
+		nodes = append(nodes, 2*mult[e]-1)
+		subdivided = append(subdivided,
+			bliss.BlissEdge{Src: e.Src, Targ: enode},
+			bliss.BlissEdge{Src: enode, Targ: e.Targ})
+	}
+	var mapping []uint
+	if directed {
+		mapping = bliss.Canonize(nodes, subdivided)
+	} else {
+		mapping = bliss.CanonizeUndirected(nodes, subdivided)
+	}
+	// the vertices keep their canonical order relative to each other
+	order := make([]int, n)
+	for v := range order {
+		order[v] = v
+	}
+	sort.Slice(order, func(i, j int) bool { return mapping[order[i]] < mapping[order[j]] })
+	rank := make([]uint32, n)
+	for r, v := range order {
+		rank[v] = uint32(r)
+	}
+	relabeled := make([]bliss.BlissEdge, len(edges))
+	for i, e := range edges {
+		relabeled[i] = bliss.BlissEdge{Src: rank[e.Src], Targ: rank[e.Targ]}
+	}
+	return relabeled
+}
@@ -19,7 +19,22 @@ package goiso
   along with goiso.  If not, see <http://www.gnu.org/licenses/>.
 */
 
-import "testing"
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+import (
+	"github.com/timtadh/goiso/bliss"
+)
 
 func TestCanon(t *testing.T) {
 	g := NewGraph(4, 4)
@@ -120,3 +135,1190 @@ func TestSubgraph(t *testing.T) {
 		t.Error("sg1 != sg2")
 	}
 }
+
+func TestSubGraphContext(t *testing.T) {
+	g := NewGraph(3, 2)
+	a := g.AddVertex(1, "blue")
+	b := g.AddVertex(2, "blue")
+	c := g.AddVertex(3, "green")
+	g.AddEdge(a, b, "purple")
+	g.AddEdge(b, c, "purple")
+	sg, _ := g.SubGraph([]int{0, 1, 2}, nil)
+	csg, _, err := g.SubGraphContext(context.Background(), []int{2, 1, 0}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sg.Label() != csg.Label() {
+		t.Errorf("expected %v got %v", sg.Label(), csg.Label())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := g.SubGraphContext(ctx, []int{0, 1, 2}, nil, nil); err == nil {
+		t.Error("expected the canonicalization to be canceled")
+	}
+	// a plain Canonicalizer must be used by the context path as well
+	calls := 0
+	g.SetCanonicalizer(CanonicalizerFunc(func(V Vertices, E Edges) ([]int, []int, bool) {
+		calls++
+		return defaultCanonicalizer.CanonicalPermutation(V, E)
+	}))
+	csg, _, err = g.SubGraphContext(context.Background(), []int{2, 1, 0}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Errorf("expected the graph's Canonicalizer to be called once got %d", calls)
+	}
+	if sg.Label() != csg.Label() {
+		t.Errorf("expected %v got %v", sg.Label(), csg.Label())
+	}
+	if _, _, err := g.SubGraphContext(ctx, []int{0, 1, 2}, nil, nil); err == nil {
+		t.Error("expected the canonicalization to be canceled")
+	}
+}
+
+func TestOrbits(t *testing.T) {
+	g := NewGraph(4, 3)
+	a := g.AddVertex(1, "root")
+	b := g.AddVertex(2, "leaf")
+	c := g.AddVertex(3, "leaf")
+	d := g.AddVertex(4, "leaf")
+	g.AddEdge(a, b, "kid")
+	g.AddEdge(a, c, "kid")
+	g.AddEdge(a, d, "other")
+	V, E := g.Orbits()
+	if !reflect.DeepEqual(V, []int{0, 1, 1, 3}) {
+		t.Errorf("unexpected vertex orbits %v", V)
+	}
+	if !reflect.DeepEqual(E, []int{0, 0, 2}) {
+		t.Errorf("unexpected edge orbits %v", E)
+	}
+	sg, _ := g.SubGraph([]int{0, 1, 2}, nil)
+	sV, sE := sg.Orbits()
+	if !reflect.DeepEqual(sE, []int{0, 0}) || sV[sg.E[0].Targ] != sV[sg.E[1].Targ] {
+		t.Errorf("unexpected subgraph orbits %v %v", sV, sE)
+	}
+}
+
+func TestSubGraphBatch(t *testing.T) {
+	g := NewGraph(4, 3)
+	a := g.AddVertex(1, "blue")
+	b := g.AddVertex(2, "blue")
+	c := g.AddVertex(3, "green")
+	d := g.AddVertex(4, "green")
+	g.AddEdge(a, b, "purple")
+	g.AddEdge(b, c, "purple")
+	g.AddEdge(c, d, "purple")
+	vidSets := [][]int{{0, 1, 2}, {3}, {3, 2, 1, 0}, {1, 0}}
+	sgs, canonized := g.SubGraphBatch(vidSets, nil)
+	if len(sgs) != len(vidSets) || len(canonized) != len(vidSets) {
+		t.Fatalf("expected %v subgraphs got %v", len(vidSets), len(sgs))
+	}
+	for i, vids := range vidSets {
+		sg, c := g.SubGraph(vids, nil)
+		if sg.Label() != sgs[i].Label() || c != canonized[i] {
+			t.Errorf("subgraph %v: expected %v got %v", i, sg.Label(), sgs[i].Label())
+		}
+	}
+}
+
+func benchGraph() (g Graph, vidSets [][]int) {
+	g = NewGraph(1000, 1000)
+	V := make([]*Vertex, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		V = append(V, g.AddVertex(i, []string{"a", "b", "c"}[i%3]))
+	}
+	for i := 1; i < len(V); i++ {
+		g.AddEdge(V[i-1], V[i], []string{"x", "y"}[i%2])
+	}
+	for i := 0; i+3 < len(V); i++ {
+		vidSets = append(vidSets, []int{i, i + 1, i + 2, i + 3})
+	}
+	return g, vidSets
+}
+
+func BenchmarkSubGraph(b *testing.B) {
+	g, vidSets := benchGraph()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, vids := range vidSets {
+			g.SubGraph(vids, nil)
+		}
+	}
+}
+
+func BenchmarkSubGraphBatch(b *testing.B) {
+	g, vidSets := benchGraph()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.SubGraphBatch(vidSets, nil)
+	}
+}
+
+func TestCanonicalizer(t *testing.T) {
+	g := NewGraph(4, 3)
+	a := g.AddVertex(1, "blue")
+	b := g.AddVertex(2, "blue")
+	c := g.AddVertex(3, "green")
+	d := g.AddVertex(4, "green")
+	g.AddEdge(a, b, "purple")
+	g.AddEdge(b, c, "purple")
+	g.AddEdge(c, d, "purple")
+	expected, _ := g.SubGraph([]int{0, 1, 2}, nil)
+	calls := 0
+	g.SetCanonicalizer(CanonicalizerFunc(func(V Vertices, E Edges) ([]int, []int, bool) {
+		calls++
+		return (&BlissCanonicalizer{}).CanonicalPermutation(V, E)
+	}))
+	sg, _ := g.SubGraph([]int{2, 1, 0}, nil)
+	if calls != 1 {
+		t.Errorf("expected the canonicalizer to be called once got %v", calls)
+	}
+	if sg.Label() != expected.Label() {
+		t.Errorf("expected %v got %v", expected.Label(), sg.Label())
+	}
+	sgs, _ := g.SubGraphBatch([][]int{{0, 1}, {2, 3}, {3}}, nil)
+	if calls != 3 || sgs[0].Label() == sgs[1].Label() {
+		t.Errorf("unexpected batch %v %v", calls, sgs)
+	}
+	can, _ := g.Canonical()
+	can.CanonicalPermutation()
+	if calls != 5 {
+		t.Errorf("expected the canonical graph to keep the canonicalizer %v", calls)
+	}
+	g.SetCanonicalizer(&BlissCanonicalizer{Options: &bliss.Options{SplittingHeuristic: bliss.SplitF}})
+	sg, _ = g.SubGraph([]int{0, 1, 2}, nil)
+	if sg2, _ := g.SubGraph([]int{2, 0, 1}, nil); sg.Label() != sg2.Label() {
+		t.Errorf("expected %v got %v", sg.Label(), sg2.Label())
+	}
+}
+
+func TestCanonicalizerContextOptions(t *testing.T) {
+	// a path whose canonical order depends on the splitting heuristic
+	g := NewGraph(4, 6)
+	var vs []*Vertex
+	for i := 0; i < 4; i++ {
+		vs = append(vs, g.AddVertex(i, "v"))
+	}
+	for _, e := range [][2]int{{0, 1}, {1, 0}, {0, 3}, {3, 0}, {1, 2}, {2, 1}} {
+		g.AddEdge(vs[e[0]], vs[e[1]], "e")
+	}
+	c := &BlissCanonicalizer{Options: bliss.DefaultOptions()}
+	Vord, Eord, _ := c.CanonicalPermutation(g.V, g.E)
+	budget := &bliss.Options{MaxNodes: 1000, Timeout: time.Minute}
+	cVord, cEord, _, err := c.CanonicalPermutationContext(context.Background(), g.V, g.E, budget)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(Vord, cVord) || !reflect.DeepEqual(Eord, cEord) {
+		t.Errorf("expected the budgets to keep the options %v %v got %v %v", Vord, Eord, cVord, cEord)
+	}
+}
+
+func TestStableLabels(t *testing.T) {
+	build := func(labels []string) *Graph {
+		g := NewGraph(3, 3)
+		g.SetStableLabels(true)
+		for _, label := range labels {
+			g.AddColor(label)
+		}
+		a := g.AddVertex(1, "a")
+		b := g.AddVertex(2, "b")
+		c := g.AddVertex(3, "b")
+		g.AddEdge(a, b, "x")
+		g.AddEdge(a, c, "y")
+		g.AddEdge(b, c, "x")
+		return &g
+	}
+	g1 := build([]string{"a", "b", "x", "y"})
+	g2 := build([]string{"y", "x", "b", "a"})
+	can1, _ := g1.Canonical()
+	can2, _ := g2.Canonical()
+	if can1.Label() != can2.Label() {
+		t.Errorf("expected equal labels\n%v\n%v", can1.Label(), can2.Label())
+	}
+	sg1, _ := g1.SubGraph([]int{0, 1, 2}, nil)
+	sg2, _ := g2.SubGraph([]int{2, 1, 0}, nil)
+	if sg1.Label() != sg2.Label() {
+		t.Errorf("expected equal labels\n%v\n%v", sg1.Label(), sg2.Label())
+	}
+	if !bytes.Equal(sg1.ShortLabel(), sg2.ShortLabel()) {
+		t.Errorf("expected equal short labels\n%v\n%v", sg1.ShortLabel(), sg2.ShortLabel())
+	}
+	sg3, _ := g1.SubGraph([]int{0, 1}, nil)
+	if bytes.Equal(sg1.ShortLabel(), sg3.ShortLabel()) {
+		t.Error("expected different short labels")
+	}
+}
+
+func TestIsomorphism(t *testing.T) {
+	g1 := NewGraph(4, 4)
+	{
+		a := g1.AddVertex(1, "a")
+		b := g1.AddVertex(2, "b")
+		c := g1.AddVertex(3, "b")
+		d := g1.AddVertex(4, "c")
+		g1.AddEdge(a, b, "x")
+		g1.AddEdge(a, c, "y")
+		g1.AddEdge(b, d, "x")
+		g1.AddEdge(c, d, "x")
+	}
+	g2 := NewGraph(4, 4)
+	{
+		d := g2.AddVertex(4, "c")
+		c := g2.AddVertex(3, "b")
+		g2.AddEdge(c, d, "x")
+		b := g2.AddVertex(2, "b")
+		a := g2.AddVertex(1, "a")
+		g2.AddEdge(a, c, "y")
+		g2.AddEdge(b, d, "x")
+		g2.AddEdge(a, b, "x")
+	}
+	vmap, emap, ok := g1.Isomorphism(&g2)
+	if !ok {
+		t.Fatal("expected the graphs to be isomorphic")
+	}
+	for i, j := range vmap {
+		if g1.V[i].Id != g2.V[j].Id {
+			t.Errorf("vertex %v mapped onto %v", g1.V[i].Id, g2.V[j].Id)
+		}
+	}
+	for i, j := range emap {
+		e, f := g1.E[i], g2.E[j]
+		if g1.V[e.Src].Id != g2.V[f.Src].Id || g1.V[e.Targ].Id != g2.V[f.Targ].Id {
+			t.Errorf("edge %v mapped onto %v", e, f)
+		}
+	}
+	g3 := NewGraph(2, 1)
+	g3.AddEdge(g3.AddVertex(1, "a"), g3.AddVertex(2, "b"), "x")
+	g4 := NewGraph(2, 1)
+	g4.AddEdge(g4.AddVertex(1, "a"), g4.AddVertex(2, "b"), "z")
+	if _, _, ok := g3.Isomorphism(&g4); ok {
+		t.Error("the edge labels differ")
+	}
+	if _, _, ok := g3.Isomorphism(&g1); ok {
+		t.Error("the sizes differ")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	g := NewGraph(4, 5)
+	{
+		a := g.AddVertex(1, "v")
+		b := g.AddVertex(2, "v")
+		c := g.AddVertex(3, "v")
+		d := g.AddVertex(4, "v")
+		g.AddEdge(a, b, "e")
+		g.AddEdge(b, c, "e")
+		g.AddEdge(c, d, "e")
+		g.AddEdge(d, a, "e")
+		g.AddEdge(a, c, "e")
+	}
+	path := NewGraph(3, 2)
+	{
+		x := path.AddVertex(1, "v")
+		y := path.AddVertex(2, "v")
+		z := path.AddVertex(3, "v")
+		path.AddEdge(x, y, "e")
+		path.AddEdge(y, z, "e")
+	}
+	check := func(embedding []int) bool {
+		for _, e := range path.E {
+			if !g.HasEdge(&g.V[embedding[e.Src]], &g.V[embedding[e.Targ]], "e") {
+				t.Errorf("%v is not an embedding", embedding)
+			}
+		}
+		return true
+	}
+	if n := NewMatcher(&g, nil).MatchGraph(&path, check); n != 6 {
+		t.Errorf("expected 6 embeddings got %v", n)
+	}
+	if n := NewMatcher(&g, &MatchOptions{Induced: true}).MatchGraph(&path, check); n != 2 {
+		t.Errorf("expected 2 induced embeddings got %v", n)
+	}
+	if n := NewMatcher(&g, &MatchOptions{Limit: 3}).MatchGraph(&path, nil); n != 3 {
+		t.Errorf("expected the limit to stop the search got %v", n)
+	}
+	stop := func([]int) bool { return false }
+	if n := NewMatcher(&g, nil).MatchGraph(&path, stop); n != 1 {
+		t.Errorf("expected the callback to stop the search got %v", n)
+	}
+	path.AddEdge(&path.V[0], &path.V[1], "f")
+	if n := NewMatcher(&g, nil).MatchGraph(&path, nil); n != 0 {
+		t.Errorf("the edge label f is not in the target got %v", n)
+	}
+
+	sg, _ := g.SubGraph([]int{0, 1, 2}, nil)
+	n := NewMatcher(&g, nil).Match(sg, func(embedding []int) bool {
+		for i, j := range embedding {
+			if sg.V[i].Id != j {
+				t.Errorf("vertex %v mapped onto %v", sg.V[i].Id, j)
+			}
+		}
+		return true
+	})
+	if n != 1 {
+		t.Errorf("expected 1 embedding got %v", n)
+	}
+}
+
+func TestMiner(t *testing.T) {
+	g := NewGraph(7, 5)
+	for i := 0; i < 2; i++ {
+		a := g.AddVertex(3*i, "a")
+		b := g.AddVertex(3*i+1, "b")
+		c := g.AddVertex(3*i+2, "c")
+		g.AddEdge(a, b, "x")
+		g.AddEdge(b, c, "y")
+	}
+	d := g.AddVertex(6, "d")
+	g.AddEdge(&g.V[2], d, "x")
+
+	mine := func(opts *MinerOptions) map[string]int {
+		patterns := make(map[string]int)
+		NewMiner(&g, opts).Mine(func(pattern *SubGraph, support int, embeddings [][]int) bool {
+			if _, has := patterns[pattern.Label()]; has {
+				t.Errorf("%v reported twice", pattern.Label())
+			}
+			patterns[pattern.Label()] = support
+			for _, embedding := range embeddings {
+				for i, vid := range embedding {
+					if pattern.V[i].Color != g.V[vid].Color {
+						t.Errorf("%v is not an embedding of %v", embedding, pattern.Label())
+					}
+				}
+			}
+			return true
+		})
+		return patterns
+	}
+	patterns := mine(&MinerOptions{MinSupport: 2})
+	if len(patterns) != 6 {
+		t.Errorf("expected 6 frequent patterns got %v", patterns)
+	}
+	for label, support := range patterns {
+		if support != 2 {
+			t.Errorf("expected %v to have support 2 got %v", label, support)
+		}
+	}
+	if patterns := mine(&MinerOptions{MinSupport: 2, MaxEdges: 1}); len(patterns) != 5 {
+		t.Errorf("expected 5 patterns with at most one edge got %v", patterns)
+	}
+	if patterns := mine(nil); len(patterns) != 10 {
+		t.Errorf("expected 10 patterns got %v", patterns)
+	}
+	calls := 0
+	NewMiner(&g, nil).Mine(func(*SubGraph, int, [][]int) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Errorf("expected mining to stop got %v calls", calls)
+	}
+}
+
+func TestSupport(t *testing.T) {
+	star := NewGraph(4, 3)
+	{
+		c := star.AddVertex(0, "a")
+		for i := 1; i <= 3; i++ {
+			star.AddEdge(c, star.AddVertex(i, "a"), "x")
+		}
+	}
+	chain := NewGraph(3, 2)
+	{
+		u := chain.AddVertex(0, "a")
+		v := chain.AddVertex(1, "a")
+		w := chain.AddVertex(2, "a")
+		chain.AddEdge(u, v, "x")
+		chain.AddEdge(v, w, "x")
+	}
+	expected := map[string]map[SupportMeasure]int{
+		"star":  {EmbeddingCount: 3, MNI: 1, MIS: 1, HarmfulOverlap: 1},
+		"chain": {EmbeddingCount: 2, MNI: 2, MIS: 1, HarmfulOverlap: 2},
+	}
+	for name, g := range map[string]*Graph{"star": &star, "chain": &chain} {
+		pattern, _ := g.SubGraph([]int{0, 1}, nil)
+		var embeddings [][]int
+		NewMatcher(g, nil).Match(pattern, func(embedding []int) bool {
+			embeddings = append(embeddings, embedding)
+			return true
+		})
+		for measure, support := range expected[name] {
+			if s := Support(pattern, embeddings, measure); s != support {
+				t.Errorf("%v: expected %v support %v got %v", name, measure, support, s)
+			}
+		}
+	}
+
+	supports := make(map[string]int)
+	NewMiner(&star, &MinerOptions{Measure: MNI}).Mine(func(pattern *SubGraph, support int, _ [][]int) bool {
+		supports[pattern.Label()] = support
+		return true
+	})
+	edge, _ := star.SubGraph([]int{0, 1}, nil)
+	if supports[edge.Label()] != 1 {
+		t.Errorf("expected the miner to use MNI got %v", supports)
+	}
+	cycle := [][]int{{1, 4}, {0, 2}, {1, 3}, {2, 4}, {3, 0}}
+	if s := maxIndependentSet(cycle); s != 2 {
+		t.Errorf("expected the 5-cycle to have an independent set of 2 got %v", s)
+	}
+}
+
+func TestGraphDB(t *testing.T) {
+	db := NewGraphDB()
+	g0 := db.NewGraph(2, 1)
+	g0.AddEdge(g0.AddVertex(1, "a"), g0.AddVertex(2, "b"), "x")
+	g1 := NewGraph(2, 1)
+	{
+		// the labels are added in a different order so the colors differ
+		b := g1.AddVertex(2, "b")
+		a := g1.AddVertex(1, "a")
+		g1.AddEdge(a, b, "x")
+	}
+	db.AddGraph(&g1)
+	g2 := db.NewGraph(3, 2)
+	{
+		c := g2.AddVertex(3, "c")
+		a := g2.AddVertex(1, "a")
+		b := g2.AddVertex(2, "b")
+		g2.AddEdge(a, b, "x")
+		g2.AddEdge(b, c, "y")
+	}
+	if g0.Labels["c"] != g2.Labels["c"] || db.Graphs[1].Labels["b"] != g0.Labels["b"] {
+		t.Error("expected the labels to be shared")
+	}
+	if !db.Canonical(0).Equals(db.Canonical(1)) {
+		t.Error("expected graphs 0 and 1 to be equal")
+	}
+	if classes := db.Classes(); !reflect.DeepEqual(classes, [][]int{{0, 1}, {2}}) {
+		t.Errorf("unexpected classes %v", classes)
+	}
+	edge := db.Canonical(0)
+	if n := db.Count(edge, nil); n != 3 {
+		t.Errorf("expected every graph to contain %v got %v", edge.Label(), n)
+	}
+	if graphs := db.Containing(db.Canonical(2), nil); !reflect.DeepEqual(graphs, []int{2}) {
+		t.Errorf("expected only graph 2 to contain the path got %v", graphs)
+	}
+}
+
+func TestGraphDBSharedColors(t *testing.T) {
+	data := `t # 0
+v 0 a
+v 1 b
+e 0 1 x
+t # 1
+v 0 a
+v 1 b
+e 0 1 y
+`
+	db, err := LoadGSpan(strings.NewReader(data), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g0, g1 := db.Graphs[0], db.Graphs[1]
+	if len(g0.colors()) != len(db.Colors) {
+		t.Errorf("expected graph 0 to see all %v labels got %v", len(db.Colors), len(g0.colors()))
+	}
+	// the edge label y was added after graph 0 was loaded
+	sg, _ := g1.SubGraph([]int{0, 1}, nil)
+	dsg, err := DecodeSubGraph(g0, sg.Encode(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dsg.Label() != sg.Label() {
+		t.Errorf("expected %v got %v", sg.Label(), dsg.Label())
+	}
+}
+
+func TestComponents(t *testing.T) {
+	build := func(order []int) Graph {
+		g := NewGraph(6, 4)
+		V := make(map[int]*Vertex)
+		for _, id := range order {
+			V[id] = g.AddVertex(id, "v")
+		}
+		g.AddEdge(V[0], V[1], "x")
+		g.AddEdge(V[1], V[0], "x")
+		g.AddEdge(V[1], V[2], "y")
+		g.AddEdge(V[4], V[5], "x")
+		return g
+	}
+	g := build([]int{0, 1, 2, 3, 4, 5})
+	components, canonized := g.Components()
+	if len(canonized) != len(components) || !canonized[1] {
+		t.Errorf("expected a canonized flag for each component got %v", canonized)
+	}
+	sizes := make([]int, 0, len(components))
+	for _, sg := range components {
+		sizes = append(sizes, len(sg.V))
+	}
+	if !reflect.DeepEqual(sizes, []int{3, 1, 2}) {
+		t.Errorf("unexpected component sizes %v", sizes)
+	}
+	strong, canonized := g.StrongComponents()
+	if len(canonized) != len(strong) {
+		t.Errorf("expected a canonized flag for each component got %v", canonized)
+	}
+	sizes = sizes[:0]
+	for _, sg := range strong {
+		sizes = append(sizes, len(sg.V))
+	}
+	if !reflect.DeepEqual(sizes, []int{2, 1, 1, 1, 1}) {
+		t.Errorf("unexpected strong component sizes %v", sizes)
+	}
+	if len(strong[0].E) != 2 {
+		t.Errorf("expected the edge leaving the component to be dropped got %v", strong[0].Label())
+	}
+	h := build([]int{5, 3, 2, 4, 1, 0})
+	if !bytes.Equal(g.ComponentLabel(), h.ComponentLabel()) {
+		t.Error("expected the component labels to be equal")
+	}
+	h.AddEdge(&h.V[1], &h.V[1], "x")
+	if bytes.Equal(g.ComponentLabel(), h.ComponentLabel()) {
+		t.Error("expected the component labels to differ")
+	}
+}
+
+func TestGraphEditing(t *testing.T) {
+	g := NewGraph(4, 4)
+	{
+		a := g.AddVertex(1, "a")
+		b := g.AddVertex(2, "b")
+		c := g.AddVertex(3, "helper")
+		d := g.AddVertex(4, "a")
+		g.AddEdge(a, c, "x")
+		g.AddEdge(c, b, "x")
+		g.AddEdge(a, b, "y")
+		g.AddEdge(b, d, "y")
+	}
+	label := g.Label()
+	g.Finalize()
+	if g.RemoveEdge(0) != nil {
+		t.Error("expected a finalized graph to be left alone")
+	}
+	c := g.Clone()
+	g.Unfinalize()
+	Vord, Eord := g.RemoveVertex(2)
+	if !reflect.DeepEqual(Vord, []int{0, 1, -1, 2}) || !reflect.DeepEqual(Eord, []int{-1, -1, 0, 1}) {
+		t.Errorf("unexpected mappings %v %v", Vord, Eord)
+	}
+	if g.ColorFrequency(g.Labels["helper"]) != 0 || g.ColorFrequency(g.Labels["x"]) != 0 {
+		t.Error("expected the removed colors to be forgotten")
+	}
+	if g.V[2].Id != 4 || g.E[1].Src != 1 || g.E[1].Targ != 2 || g.E[1].Idx != 1 {
+		t.Errorf("expected the indices to be compacted got %v %v", g.V, g.E)
+	}
+	if len(g.Kids[0]) != 1 || g.Kids[0][0].Targ != 1 || len(g.Parents[2]) != 1 {
+		t.Error("expected Kids and Parents to be rebuilt")
+	}
+	g.Relabel(2, "b")
+	g.RelabelEdge(1, "x")
+	if g.Colors[g.V[2].Color] != "b" || g.Colors[g.Kids[1][0].Color] != "x" {
+		t.Error("expected the labels to change")
+	}
+	if Eord := g.RemoveEdge(0); !reflect.DeepEqual(Eord, []int{-1, 0}) || len(g.Kids[0]) != 0 {
+		t.Errorf("unexpected mapping %v", Eord)
+	}
+	if c.Label() != label || c.AddVertex(5, "c") == nil {
+		t.Error("expected the clone to be an unfinalized copy")
+	}
+
+	// a canonical graph must keep its color frequencies so it can be edited
+	ng, _ := c.Canonical()
+	if ng.ColorFrequency(ng.Labels["a"]) != 2 {
+		t.Errorf("expected the canonical graph to count its colors")
+	}
+	ng.Unfinalize()
+	idx := 0
+	for i := range ng.V {
+		if ng.V[i].Id == 1 {
+			idx = i
+		}
+	}
+	ng.Relabel(idx, "z")
+	if ng.ColorFrequency(ng.Labels["a"]) != 1 || ng.ColorFrequency(ng.Labels["z"]) != 1 {
+		t.Error("expected the relabeling to be counted")
+	}
+	ng.RemoveVertex(idx)
+	if ng.ColorFrequency(ng.Labels["z"]) != 0 || len(ng.V) != 4 {
+		t.Error("expected the vertex to be removed")
+	}
+	if nc := ng.Clone(); nc.ColorFrequency(ng.Labels["b"]) != 1 {
+		t.Error("expected the clone to count its colors")
+	}
+	// a graph which never counted its colors is recounted when reopened
+	bare, _ := c.Canonical()
+	bare.colorFreq = nil
+	bare.Unfinalize()
+	if Vord, _ := bare.RemoveVertex(0); Vord == nil || bare.ColorFrequency(bare.Labels["b"]) != 1 {
+		t.Error("expected the colors to be recounted")
+	}
+}
+
+func TestLoadVEG(t *testing.T) {
+	g := NewGraph(3, 3)
+	a := g.AddVertex(12, "blue")
+	b := g.AddVertex(7, "blue")
+	c := g.AddVertex(57, "green\t\"quoted\"")
+	g.AddEdge(a, b, "purple")
+	g.AddEdge(b, c, "purple")
+	g.AddEdge(c, c, "red")
+	sg, _ := g.SubGraph([]int{0, 1, 2}, nil)
+	veg := sg.VEG(map[int]map[string]interface{}{1: {"start_line": 42}})
+
+	h, attrs, err := LoadVEGWithAttrs(bytes.NewReader(veg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hsg, _ := h.SubGraph([]int{0, 1, 2}, nil)
+	if hsg.Label() != sg.Label() {
+		t.Errorf("expected %v got %v", sg.Label(), hsg.Label())
+	}
+	for i := range h.V {
+		if h.V[i].Id == 7 && fmt.Sprint(attrs[i]["start_line"]) != "42" {
+			t.Errorf("expected the attributes to be kept got %v", attrs)
+		}
+	}
+	if !bytes.Equal(hsg.VEG(attrs), veg) {
+		t.Errorf("expected the VEG to round trip\n%s\n%s", veg, hsg.VEG(attrs))
+	}
+
+	bad := []string{
+		"vertex\t{\"id\": 1, \"label\": \"a\"}\nvertex {\"id\": 2}",
+		"vertex\t{\"id\": 1, \"label\": \"a\"}\nvertex\t{\"id\": 1.5, \"label\": \"a\"}",
+		"vertex\t{\"id\": 1, \"label\": \"a\"}\n\nedge\t{\"src\": 1, \"targ\": 3, \"label\": \"a\"}",
+		"vertex\t{\"id\": 1, \"label\": \"a\"}\nvertex\t{\"id\": 1, \"label\": \"a\"}",
+		"vertex\t{\"id\": 1, \"label\": \"a\"}\nvertex\t{\"id\": 2, \"label\": \"a\"",
+		"vertex\t{\"id\": 1, \"label\": \"a\"}\nnode\t{\"id\": 2, \"label\": \"a\"}",
+		"vertex\t{\"id\": 1, \"label\": \"a\"}\nvertex\t{\"id\": 2, \"label\": \"a\"} junk",
+		"vertex\t{\"id\": 1, \"label\": \"a\"}\nvertex\t{\"id\": 2, \"label\": \"a\"}}",
+		"vertex\t{\"id\": 1, \"label\": \"a\"}\nvertex\t{\"id\": 2, \"label\": \"a\"}{}",
+	}
+	for _, veg := range bad {
+		_, err := LoadVEG(bytes.NewReader([]byte(veg)))
+		if err == nil || !strings.Contains(err.Error(), "line 2") && !strings.Contains(err.Error(), "line 3") {
+			t.Errorf("expected a line numbered error for %q got %v", veg, err)
+		}
+	}
+	r := io.MultiReader(strings.NewReader("vertex\t{\"id\": 1, \"label\": \"a\"}\n"), iotest.ErrReader(io.ErrUnexpectedEOF))
+	if _, err := LoadVEG(r); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected a line numbered read error got %v", err)
+	}
+}
+
+func TestGraph6(t *testing.T) {
+	graphs, err := ReadGraph6(strings.NewReader("C~\n:Fa@x^\n&B?_\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(graphs[0].V) != 4 || len(graphs[0].E) != 12 || len(graphs[2].E) != 1 {
+		t.Errorf("unexpected graphs %v %v", graphs[0], graphs[2])
+	}
+	if _, err := ReadGraph6(strings.NewReader(":~~?~~~~~\n")); err == nil {
+		t.Error("expected a line announcing 2^30 vertices to be rejected")
+	}
+	formats := []bliss.Graph6Format{bliss.Graph6, bliss.Sparse6, bliss.Digraph6}
+	for i, expected := range []string{"C~\n", ":Fa@x^\n", "&B?_\n"} {
+		var buf bytes.Buffer
+		if err := graphs[i].WriteGraph6(&buf, formats[i], false); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != expected {
+			t.Errorf("expected %q got %q", expected, buf.String())
+		}
+	}
+
+	path := func(order []int) *Graph {
+		g := NewGraph(4, 3)
+		for _, id := range order {
+			g.AddVertex(id, "")
+		}
+		for i := 0; i < 3; i++ {
+			u, v := -1, -1
+			for idx, id := range order {
+				if id == i {
+					u = idx
+				} else if id == i+1 {
+					v = idx
+				}
+			}
+			g.AddEdge(&g.V[u], &g.V[v], "")
+		}
+		return &g
+	}
+	lines := make(map[string]bool)
+	canon := make(map[string]bool)
+	for _, order := range [][]int{{0, 1, 2, 3}, {2, 0, 3, 1}, {3, 1, 0, 2}} {
+		for _, format := range formats {
+			var buf bytes.Buffer
+			path(order).WriteGraph6(&buf, format, false)
+			lines[buf.String()] = true
+			buf.Reset()
+			path(order).WriteGraph6(&buf, format, true)
+			canon[buf.String()] = true
+		}
+	}
+	if len(canon) != 3 || len(lines) == 3 {
+		t.Errorf("expected one canonical line per format got %v %v", canon, lines)
+	}
+
+	// the path 0-1-2 with the edge 0-1 doubled and optionally a loop on 2
+	multi := func(order []int, loop bool) *Graph {
+		g := NewGraph(3, 4)
+		for _, id := range order {
+			g.AddVertex(id, "")
+		}
+		idx := make(map[int]*Vertex)
+		for i := range g.V {
+			idx[g.V[i].Id] = &g.V[i]
+		}
+		g.AddEdge(idx[0], idx[1], "")
+		g.AddEdge(idx[0], idx[1], "")
+		g.AddEdge(idx[1], idx[2], "")
+		if loop {
+			g.AddEdge(idx[2], idx[2], "")
+		}
+		return &g
+	}
+	for _, loop := range []bool{false, true} {
+		canon = make(map[string]bool)
+		for _, order := range [][]int{{0, 1, 2}, {1, 0, 2}, {2, 1, 0}, {1, 2, 0}, {0, 2, 1}, {2, 0, 1}} {
+			var buf bytes.Buffer
+			multi(order, loop).WriteGraph6(&buf, bliss.Sparse6, true)
+			canon[buf.String()] = true
+		}
+		if len(canon) != 1 {
+			t.Errorf("expected one canonical sparse6 line for the multigraph got %v", canon)
+		}
+		for line := range canon {
+			_, n, edges, err := bliss.DecodeGraph6([]byte(line))
+			if err != nil || n != 3 || (loop && len(edges) != 4) || (!loop && len(edges) != 3) {
+				t.Errorf("expected the loop and the parallel edges to be kept got %v %v %v", n, edges, err)
+			}
+		}
+	}
+}
+
+func TestGraphML(t *testing.T) {
+	g := NewGraph(3, 3)
+	a := g.AddVertex(12, "blue")
+	b := g.AddVertex(7, "<blue & green>")
+	c := g.AddVertex(57, "green")
+	g.AddEdge(a, b, "purple")
+	g.AddEdge(b, c, "purple")
+	g.AddEdge(c, a, "\"red\"")
+	attrs := map[int]map[string]interface{}{
+		0: {"start_line": 42, "file": "a.go"},
+		2: {"start_line": 43, "weight": 1.5},
+	}
+	var buf bytes.Buffer
+	if err := g.WriteGraphML(&buf, attrs); err != nil {
+		t.Fatal(err)
+	}
+	h, hattrs, err := ReadGraphML(&buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Label() != g.Label() {
+		t.Errorf("expected %v got %v", g.Label(), h.Label())
+	}
+	for i := range g.V {
+		if h.V[i].Id != g.V[i].Id {
+			t.Errorf("expected vertex %v to have id %v got %v", i, g.V[i].Id, h.V[i].Id)
+		}
+	}
+	if !reflect.DeepEqual(hattrs, attrs) {
+		t.Errorf("expected %v got %v", attrs, hattrs)
+	}
+
+	sg, _ := g.SubGraph([]int{1, 2}, nil)
+	buf.Reset()
+	if err := sg.WriteGraphML(&buf, attrs); err != nil {
+		t.Fatal(err)
+	}
+	if h, _, err := ReadGraphML(&buf, nil); err != nil || len(h.V) != 2 || len(h.E) != 1 {
+		t.Errorf("expected the subgraph got %v %v", h, err)
+	}
+
+	doc := `<?xml version="1.0"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="d0" for="node" attr.name="kind" attr.type="string"><default>op</default></key>
+  <key id="d1" for="edge" attr.name="rel" attr.type="string"/>
+  <graph edgedefault="undirected">
+    <edge source="x" target="y"><data key="d1">calls</data></edge>
+    <node id="x"/>
+    <node id="y"><data key="d0">call</data></node>
+  </graph>
+</graphml>`
+	h, _, err = ReadGraphML(strings.NewReader(doc), &GraphMLOptions{VertexLabel: "kind", EdgeLabel: "rel"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.E) != 2 || h.Colors[h.V[0].Color] != "op" || h.Colors[h.V[1].Color] != "call" || h.Colors[h.E[0].Color] != "calls" {
+		t.Errorf("unexpected graph %v", h)
+	}
+
+	// keys sharing an attr.name are resolved in document order and the
+	// defaults are filled into the attributes
+	doc = `<?xml version="1.0"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="w1" for="node" attr.name="weight" attr.type="int"><default>1</default></key>
+  <key id="w2" for="node" attr.name="weight" attr.type="string"/>
+  <key id="c" for="node" attr.name="color" attr.type="string"><default>red</default></key>
+  <key id="e" for="edge" attr.name="cost" attr.type="int"><default>3</default></key>
+  <graph edgedefault="directed">
+    <node id="x"/>
+    <node id="y"><data key="w2">heavy</data><data key="c">blue</data></node>
+    <node id="z"><data key="w1">5</data><data key="w2">heavy</data></node>
+  </graph>
+</graphml>`
+	expected := map[int]map[string]interface{}{
+		0: {"weight": 1, "color": "red"},
+		1: {"weight": "heavy", "color": "blue"},
+		2: {"weight": 5, "color": "red"},
+	}
+	for i := 0; i < 10; i++ {
+		_, hattrs, err := ReadGraphML(strings.NewReader(doc), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(hattrs, expected) {
+			t.Fatalf("expected %v got %v", expected, hattrs)
+		}
+	}
+	_, _, err = ReadGraphML(strings.NewReader("<graphml>\n<graph>\n<node/>\n</graph></graphml>"), nil)
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("expected an error on line 3 got %v", err)
+	}
+}
+
+func TestLoadDot(t *testing.T) {
+	g := NewGraph(3, 3)
+	a := g.AddVertex(12, "blue")
+	b := g.AddVertex(7, "say \"hi\"\nthere")
+	c := g.AddVertex(57, "green")
+	g.AddEdge(a, b, "purple")
+	g.AddEdge(b, c, "a \"quoted\" edge")
+	g.AddEdge(c, a, "red")
+	h, err := LoadDot(strings.NewReader(g.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.String() != g.String() {
+		t.Errorf("expected\n%v\ngot\n%v", g, h)
+	}
+
+	// backslashes must survive, also when followed by n or a quote
+	b2 := NewGraph(3, 2)
+	x := b2.AddVertex(1, "a\\")
+	y := b2.AddVertex(2, "x\\ny")
+	z := b2.AddVertex(3, "\\\"\\")
+	b2.AddEdge(x, y, "\\")
+	b2.AddEdge(y, z, "tab\\t")
+	h, err = LoadDot(strings.NewReader(b2.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.String() != b2.String() || h.Colors[h.V[1].Color] != "x\\ny" {
+		t.Errorf("expected\n%v\ngot\n%v", b2.String(), h)
+	}
+
+	sg, _ := g.SubGraph([]int{0, 1, 2}, nil)
+	attrs := map[int]map[string]interface{}{1: {"start_line": 42, "file": "C:\\x.go"}}
+	h, vattrs, _, err := LoadDotWithAttrs(strings.NewReader(sg.StringWithAttrs(attrs)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hsg, _ := h.SubGraph([]int{0, 1, 2}, nil)
+	if hsg.Label() != sg.Label() {
+		t.Errorf("expected %v got %v", sg.Label(), hsg.Label())
+	}
+	for i := range h.V {
+		if h.V[i].Id != 7 {
+			continue
+		}
+		a := vattrs[i]
+		if a["idx"] != "1" || a["start_line"] != "42" || a["file"] != "C:\\x.go" {
+			t.Errorf("expected the attributes to be kept got %v", a)
+		}
+	}
+
+	dot := `/* a hand drawn pattern */
+graph pattern {
+    node [label=op];
+    x -- y -- z [label=flow, weight=2];  // a chain
+    subgraph cluster { edge [label=call]; y -- "w" }
+    z [label="ret" + "urn"];
+}`
+	h, vattrs, eattrs, err := LoadDotWithAttrs(strings.NewReader(dot))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.V) != 4 || len(h.E) != 6 || h.Colors[h.V[2].Color] != "return" || h.Colors[h.E[4].Color] != "call" {
+		t.Errorf("unexpected graph %v", h)
+	}
+	if vattrs[0]["name"] != "x" || eattrs[0]["weight"] != "2" {
+		t.Errorf("unexpected attributes %v %v", vattrs, eattrs)
+	}
+	_, err = LoadDot(strings.NewReader("digraph {\n  a -> b\n  b -- c\n}"))
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("expected an error on line 3 got %v", err)
+	}
+}
+
+func TestGSpan(t *testing.T) {
+	data := `t # 0
+v 0 C
+v 1 C
+v 2 O
+e 0 1 1
+e 1 2 2
+
+t # 1
+v 0 O
+v 1 C
+v 2 C
+x 0 1
+e 1 2 1
+e 2 0 2
+t # -1
+t # 2
+`
+	db, err := LoadGSpan(strings.NewReader(data), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(db.Graphs) != 2 || len(db.Graphs[0].E) != 4 || db.Graphs[1].Labels["O"] != db.Graphs[0].Labels["O"] {
+		t.Errorf("unexpected graphs %v", db.Graphs)
+	}
+	if classes := db.Classes(); len(classes) != 1 {
+		t.Errorf("expected the graphs to be isomorphic got %v", classes)
+	}
+	var buf bytes.Buffer
+	sg := db.Canonical(1)
+	if err := sg.WriteGSpan(&buf, 7, 2, true); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "t # 7 * 2\n") || strings.Count(buf.String(), "e ") != 2 {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+	db2, err := LoadGSpan(&buf, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := db2.Graphs[0].Isomorphism(db.Graphs[1]); !ok {
+		t.Errorf("expected %v got %v", db.Graphs[1], db2.Graphs[0])
+	}
+	for _, labels := range [][2]string{{"", "a"}, {"C", ""}, {"C O", "a"}} {
+		g := NewGraph(2, 1)
+		g.AddEdge(g.AddVertex(1, labels[0]), g.AddVertex(2, "C"), labels[1])
+		sg, _ := g.SubGraph([]int{0, 1}, nil)
+		buf.Reset()
+		if err := sg.WriteGSpan(&buf, 0, -1, false); err == nil || buf.Len() != 0 {
+			t.Errorf("expected the labels %q to be rejected got %q", labels, buf.String())
+		}
+	}
+	_, err = LoadGSpan(strings.NewReader("t # 0\nv 0 C\ne 0 1 a\n"), false)
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("expected an error on line 3 got %v", err)
+	}
+}
+
+func TestEncodeSubGraph(t *testing.T) {
+	build := func(extra bool) *Graph {
+		g := NewGraph(4, 4)
+		if extra {
+			// shifts the color ids
+			g.AddColor("unused")
+		}
+		a := g.AddVertex(12, "blue")
+		b := g.AddVertex(7, "blue")
+		c := g.AddVertex(57, "green")
+		g.AddVertex(9, "green")
+		g.AddEdge(a, b, "purple")
+		g.AddEdge(b, c, "red")
+		g.AddEdge(c, a, "purple")
+		return &g
+	}
+	g := build(false)
+	sg, _ := g.SubGraph([]int{0, 1, 2}, nil)
+	check := func(name string, data []byte, g *Graph) {
+		dsg, err := DecodeSubGraph(g, data)
+		if err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+		if dsg.Label() != sg.Label() || g != nil && dsg.String() != sg.String() {
+			t.Errorf("%v: expected %v got %v", name, sg.Label(), dsg.Label())
+		}
+		if g != nil && (!dsg.Equals(sg) || !dsg.HasEdge(ColoredArc{Arc{0, 1}, g.Labels["purple"]})) {
+			t.Errorf("%v: expected the subgraphs to be equal", name)
+		}
+	}
+	check("v1", sg.Serialize(), g)
+	check("v2", sg.Encode(false), g)
+	check("v2 with labels", sg.Encode(true), g)
+	check("v2 without the graph", sg.Encode(true), nil)
+	h := build(true)
+	hsg, err := DecodeSubGraph(h, sg.Encode(true))
+	if err != nil || hsg.Label() != sg.Label() || hsg.V[0].Color == sg.V[0].Color {
+		t.Errorf("expected the colors to be translated got %v %v", hsg, err)
+	}
+
+	data := sg.Encode(true)
+	bad := map[string][]byte{
+		"empty":     {},
+		"truncated": data[:len(data)-5],
+		"corrupted": append(append([]byte{}, data[:5]...), append([]byte{data[5] ^ 1}, data[6:]...)...),
+		"version":   append([]byte{serialMarkV2, 3}, data[2:]...),
+		"v1 size":   sg.Serialize()[:20],
+	}
+	for name, data := range bad {
+		if _, err := DecodeSubGraph(g, data); err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+	}
+	if _, err := DecodeSubGraph(nil, sg.Encode(false)); err == nil {
+		t.Error("expected an error without labels or a graph")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected DeserializeSubGraph to panic")
+		}
+	}()
+	DeserializeSubGraph(g, bad["corrupted"])
+}
+
+func TestGraphBinary(t *testing.T) {
+	g := NewGraph(4, 4)
+	{
+		a := g.AddVertex(-12, "blue")
+		b := g.AddVertex(7, "blue")
+		c := g.AddVertex(57, "green")
+		d := g.AddVertex(9, "green")
+		g.AddEdge(a, b, "purple")
+		g.AddEdge(b, c, "red")
+		g.AddEdge(c, a, "purple")
+		g.AddEdge(d, d, "purple")
+	}
+	sg, _ := g.SubGraph([]int{0, 1, 2}, nil)
+	g.Finalize()
+	data, err := g.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var h Graph
+	if err := h.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if h.String() != g.String() || h.Label() != g.Label() || !reflect.DeepEqual(h.Colors, g.Colors) {
+		t.Errorf("expected\n%v\ngot\n%v", g, h)
+	}
+	if h.AddVertex(1, "x") != nil {
+		t.Error("expected the graph to be finalized")
+	}
+	for i := range g.V {
+		if !reflect.DeepEqual(h.Kids[i], g.Kids[i]) || !reflect.DeepEqual(h.Parents[i], g.Parents[i]) {
+			t.Errorf("expected the adjacency of %v to be rebuilt", i)
+		}
+	}
+	if h.ColorFrequency(h.Labels["purple"]) != 3 {
+		t.Errorf("expected the color frequencies to be rebuilt got %v", h.ColorFrequency(h.Labels["purple"]))
+	}
+	hsg, err := DecodeSubGraph(&h, sg.Serialize())
+	if err != nil || hsg.Label() != sg.Label() {
+		t.Errorf("expected the subgraph to decode against the graph read got %v %v", hsg, err)
+	}
+
+	var buf bytes.Buffer
+	if n, err := g.WriteTo(&buf); err != nil || n != int64(len(data)) {
+		t.Errorf("expected %v bytes written got %v %v", len(data), n, err)
+	}
+	buf.WriteString("more")
+	if _, err := ReadGraph(&buf); err != nil || buf.String() != "more" {
+		t.Errorf("expected to read up to the end of the graph got %q %v", buf.String(), err)
+	}
+	// graphs concatenated in one stream
+	buf.Reset()
+	g.WriteTo(&buf)
+	h.WriteTo(&buf)
+	buf.WriteString("more")
+	for _, r := range []io.Reader{bytes.NewReader(buf.Bytes()), bufio.NewReader(bytes.NewReader(buf.Bytes()))} {
+		for i := 0; i < 2; i++ {
+			if rg, err := ReadGraph(r); err != nil || rg.Label() != g.Label() {
+				t.Errorf("expected graph %v of the stream got %v %v", i, rg, err)
+			}
+		}
+		if rest, _ := io.ReadAll(r); string(rest) != "more" {
+			t.Errorf("expected to read up to the end of the graphs got %q", rest)
+		}
+	}
+	// truncated or malformed input is an error not a panic
+	for _, bad := range [][]byte{
+		{0xac, 0x01, 0x00, 0x00, 0x01},
+		{0xac, 0x01, 0x00, 0x00, 0x01, 0x02},
+		{0xac, 0x01, 0x00, 0x01, 0x01, 'a', 0x01, 0x02, 0x05},
+		{0xac, 0x01, 0x00, 0x01, 0x01, 'a', 0x01, 0x02, 0x00, 0x01, 0x00, 0x03},
+		{0xac, 0x01, 0x00, 0x00, 0x02, 0xff, 0xff},
+	} {
+		if _, err := ReadGraph(bytes.NewReader(bad)); err == nil {
+			t.Errorf("expected an error for %x", bad)
+		}
+		if err := h.UnmarshalBinary(bad); err == nil {
+			t.Errorf("expected an error for %x", bad)
+		}
+	}
+	for i := range data {
+		corrupted := append([]byte{}, data...)
+		corrupted[i] ^= 0x10
+		if err := h.UnmarshalBinary(corrupted); err == nil {
+			t.Errorf("expected an error for a corruption at %v", i)
+		}
+	}
+	if err := h.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Error("expected an error for truncated data")
+	}
+}
+
+// A graph with a million edges.
+func largeGraph() *Graph {
+	const nV, nE = 100000, 1000000
+	g := NewGraph(nV, nE)
+	labels := make([]string, 64)
+	for i := range labels {
+		labels[i] = fmt.Sprintf("label-%d", i)
+	}
+	V := make([]*Vertex, 0, nV)
+	for i := 0; i < nV; i++ {
+		V = append(V, g.AddVertex(i, labels[i%len(labels)]))
+	}
+	for i := 0; i < nE; i++ {
+		g.AddEdge(V[i%nV], V[(i*7919+i/nV)%nV], labels[(i/3)%len(labels)])
+	}
+	return &g
+}
+
+func BenchmarkReadGraph(b *testing.B) {
+	data, err := largeGraph().MarshalBinary()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("bufio", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			if _, err := ReadGraph(bufio.NewReaderSize(bytes.NewReader(data), 1<<16)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("ByteReader", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			if _, err := ReadGraph(bytes.NewReader(data)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,165 @@
+package goiso
+
+/*
+  Copyright (c) 2014 Tim Henderson
+  Released under the GNU General Public License version 3.
+
+  This file is part of goiso a wrapper around bliss.
+
+  bliss is free software: you can redistribute it and/or modify
+  it under the terms of the GNU General Public License version 3
+  as published by the Free Software Foundation.
+
+  bliss is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU General Public License for more details.
+
+  You should have received a copy of the GNU General Public License
+  along with goiso.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// A collection of graphs (transactions) sharing one label dictionary. The
+// color of a label is the same in every graph of the database so SubGraphs
+// of different graphs can be compared with Equals and ShortLabel. Graphs are
+// added with NewGraph or AddGraph. The dictionary is db.Colors: the Colors of
+// a graph in the database is only brought up to date when that graph adds a
+// label so it may lack the labels added since by other graphs.
+type GraphDB struct {
+	Graphs []*Graph
+	Colors []string
+	Labels map[string]int
+}
+
+// Construct an empty database.
+func NewGraphDB() *GraphDB {
+	return &GraphDB{
+		Graphs: make([]*Graph, 0, 10),
+		Colors: make([]string, 0, 10),
+		Labels: make(map[string]int),
+	}
+}
+
+// Construct a new graph with V vertices and E edges in the database. The
+// graph's labels are interned in the database's dictionary.
+func (db *GraphDB) NewGraph(V, E int) *Graph {
+	g := NewGraph(V, E)
+	g.Colors = db.Colors
+	g.Labels = db.Labels
+	g.db = db
+	db.Graphs = append(db.Graphs, &g)
+	return &g
+}
+
+// Copies g into the database re-interning its labels. The vertex Ids and
+// the order of the vertices and edges are preserved.
+func (db *GraphDB) AddGraph(g *Graph) *Graph {
+	ng := db.NewGraph(len(g.V), len(g.E))
+	ng.canonicalizer = g.canonicalizer
+	ng.stable = g.stable
+	for i := range g.V {
+		ng.AddVertex(g.V[i].Id, g.colors()[g.V[i].Color])
+	}
+	for i := range g.E {
+		e := &g.E[i]
+		ng.AddEdge(&ng.V[e.Src], &ng.V[e.Targ], g.colors()[e.Color])
+	}
+	return ng
+}
+
+// Interns the label returning its color.
+func (db *GraphDB) AddColor(label string) int {
+	if cid, has := db.Labels[label]; has {
+		return cid
+	}
+	cid := len(db.Colors)
+	db.Labels[label] = cid
+	db.Colors = append(db.Colors, label)
+	return cid
+}
+
+// AddColor for graphs in a database. The graph's tables are refreshed as
+// other graphs may have grown the dictionary.
+func (g *Graph) addSharedColor(label string) int {
+	cid := g.db.AddColor(label)
+	g.Colors = g.db.Colors
+	g.Labels = g.db.Labels
+	for len(g.colorFreq) <= cid {
+		g.colorFreq = append(g.colorFreq, 0)
+	}
+	g.colorFreq[cid] += 1
+	return cid
+}
+
+// The label dictionary of the graph. Graphs in a database read it through
+// the database as other graphs may have grown it since (see GraphDB).
+func (g *Graph) colors() []string {
+	if g.db != nil {
+		return g.db.Colors
+	}
+	return g.Colors
+}
+
+// The canonical form of the i'th graph as a SubGraph of all of its vertices.
+// Canonical forms of isomorphic graphs in the database have the same
+// ShortLabel.
+func (db *GraphDB) Canonical(i int) *SubGraph {
+	g := db.Graphs[i]
+	vids := make([]int, len(g.V))
+	for j := range vids {
+		vids[j] = j
+	}
+	sg, _ := g.SubGraph(vids, nil)
+	return sg
+}
+
+// The canonical forms of every graph. See Canonical.
+func (db *GraphDB) Canonize() []*SubGraph {
+	sgs := make([]*SubGraph, len(db.Graphs))
+	for i := range db.Graphs {
+		sgs[i] = db.Canonical(i)
+	}
+	return sgs
+}
+
+// Groups the graphs into isomorphism classes. Each class lists the indices
+// of its graphs in increasing order and the classes are ordered by their
+// first graph. Taking the first graph of each class deduplicates the
+// database.
+func (db *GraphDB) Classes() [][]int {
+	classes := make([][]int, 0, len(db.Graphs))
+	index := make(map[string]int)
+	for i, sg := range db.Canonize() {
+		label := string(sg.ShortLabel())
+		if c, has := index[label]; has {
+			classes[c] = append(classes[c], i)
+		} else {
+			index[label] = len(classes)
+			classes = append(classes, []int{i})
+		}
+	}
+	return classes
+}
+
+// The indices of the graphs containing the pattern. The pattern may come
+// from any graph (its labels are matched by string). opts chooses induced
+// or non induced containment, its Limit is ignored. opts may be nil.
+func (db *GraphDB) Containing(pattern *SubGraph, opts *MatchOptions) []int {
+	o := MatchOptions{Limit: 1}
+	if opts != nil {
+		o.Induced = opts.Induced
+	}
+	graphs := make([]int, 0, len(db.Graphs))
+	for i, g := range db.Graphs {
+		if NewMatcher(g, &o).Match(pattern, nil) > 0 {
+			graphs = append(graphs, i)
+		}
+	}
+	return graphs
+}
+
+// How many graphs contain the pattern (its transaction support). See
+// Containing.
+func (db *GraphDB) Count(pattern *SubGraph, opts *MatchOptions) int {
+	return len(db.Containing(pattern, opts))
+}
@@ -0,0 +1,410 @@
+package goiso
+
+/*
+  Copyright (c) 2014 Tim Henderson
+  Released under the GNU General Public License version 3.
+
+  This file is part of goiso a wrapper around bliss.
+
+  bliss is free software: you can redistribute it and/or modify
+  it under the terms of the GNU General Public License version 3
+  as published by the Free Software Foundation.
+
+  bliss is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU General Public License for more details.
+
+  You should have received a copy of the GNU General Public License
+  along with goiso.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// Write the graph in GraphML. Each vertex has its label and its Id as data
+// along with any attributes for it in attrs (keyed by vertex Idx, see
+// SubGraph.StringWithAttrs). Each edge has its label. The GraphML node ids
+// are n<Idx>. attrs may be nil.
+func (g *Graph) WriteGraphML(w io.Writer, attrs map[int]map[string]interface{}) error {
+	nodes := make([]graphMLNode, len(g.V))
+	for i := range g.V {
+		nodes[i] = graphMLNode{g.V[i].Id, g.colors()[g.V[i].Color], attrs[i]}
+	}
+	edges := make([]graphMLEdge, len(g.E))
+	for i := range g.E {
+		edges[i] = graphMLEdge{g.E[i].Src, g.E[i].Targ, g.colors()[g.E[i].Color]}
+	}
+	return writeGraphML(w, nodes, edges)
+}
+
+// Write the subgraph in GraphML. The vertices have the Ids of the vertices
+// in the parent graph and attrs is keyed by the vertex Idx in the parent
+// graph just as in SubGraph.VEG. See Graph.WriteGraphML.
+func (sg *SubGraph) WriteGraphML(w io.Writer, attrs map[int]map[string]interface{}) error {
+	nodes := make([]graphMLNode, len(sg.V))
+	for i := range sg.V {
+		v := &sg.V[i]
+		nodes[i] = graphMLNode{sg.G.V[v.Id].Id, sg.G.colors()[v.Color], attrs[v.Id]}
+	}
+	edges := make([]graphMLEdge, len(sg.E))
+	for i := range sg.E {
+		edges[i] = graphMLEdge{sg.E[i].Src, sg.E[i].Targ, sg.G.colors()[sg.E[i].Color]}
+	}
+	return writeGraphML(w, nodes, edges)
+}
+
+type graphMLNode struct {
+	id    int
+	label string
+	attrs map[string]interface{}
+}
+
+type graphMLEdge struct {
+	src, targ int
+	label     string
+}
+
+func writeGraphML(w io.Writer, nodes []graphMLNode, edges []graphMLEdge) error {
+	// the GraphML type of each attribute is the narrowest fitting every value
+	types := make(map[string]string)
+	for _, n := range nodes {
+		for name, value := range n.attrs {
+			if name == "id" || name == "label" {
+				continue
+			}
+			t := graphMLType(value)
+			if prev, has := types[name]; has && prev != t {
+				if (prev == "long" || prev == "double") && (t == "long" || t == "double") {
+					t = "double"
+				} else {
+					t = "string"
+				}
+			}
+			types[name] = t
+		}
+	}
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := bufio.NewWriter(w)
+	escape := func(s string) string {
+		var buf bytes.Buffer
+		xml.EscapeText(&buf, []byte(s))
+		return buf.String()
+	}
+	fmt.Fprintln(out, `<?xml version="1.0" encoding="UTF-8"?>`)
+	fmt.Fprintln(out, `<graphml xmlns="http://graphml.graphdrawing.org/xmlns">`)
+	fmt.Fprintln(out, `  <key id="label" for="node" attr.name="label" attr.type="string"/>`)
+	fmt.Fprintln(out, `  <key id="id" for="node" attr.name="id" attr.type="long"/>`)
+	fmt.Fprintln(out, `  <key id="elabel" for="edge" attr.name="label" attr.type="string"/>`)
+	for i, name := range names {
+		fmt.Fprintf(out, "  <key id=\"a%d\" for=\"node\" attr.name=\"%s\" attr.type=\"%s\"/>\n", i, escape(name), types[name])
+	}
+	fmt.Fprintln(out, `  <graph edgedefault="directed">`)
+	for i, n := range nodes {
+		fmt.Fprintf(out, "    <node id=\"n%d\">", i)
+		fmt.Fprintf(out, "<data key=\"label\">%s</data>", escape(n.label))
+		fmt.Fprintf(out, "<data key=\"id\">%d</data>", n.id)
+		for j, name := range names {
+			if value, has := n.attrs[name]; has {
+				fmt.Fprintf(out, "<data key=\"a%d\">%s</data>", j, escape(fmt.Sprint(value)))
+			}
+		}
+		fmt.Fprintln(out, "</node>")
+	}
+	for _, e := range edges {
+		fmt.Fprintf(out, "    <edge source=\"n%d\" target=\"n%d\"><data key=\"elabel\">%s</data></edge>\n",
+			e.src, e.targ, escape(e.label))
+	}
+	fmt.Fprintln(out, "  </graph>")
+	fmt.Fprintln(out, "</graphml>")
+	return out.Flush()
+}
+
+func graphMLType(value interface{}) string {
+	switch v := value.(type) {
+	case bool:
+		return "boolean"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "long"
+	case float32, float64:
+		return "double"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "long"
+		}
+		if _, err := v.Float64(); err == nil {
+			return "double"
+		}
+	}
+	return "string"
+}
+
+// Options for ReadGraphML. A nil *GraphMLOptions means the defaults.
+type GraphMLOptions struct {
+	// The attribute (matched by attr.name or by key id) holding the vertex
+	// labels. Defaults to "label".
+	VertexLabel string
+	// The attribute holding the edge labels. Defaults to "label".
+	EdgeLabel string
+	// The integer attribute holding the vertex Ids. Defaults to "id". Vertices
+	// without one get their Idx as their Id.
+	VertexId string
+}
+
+// Reads the first graph of a GraphML document. The document is read as a
+// stream of tokens so it does not need to fit in memory twice. Vertices are
+// added in document order, edges after all of the vertices. Undirected edges
+// become an edge in each direction. Vertices and edges without a label get
+// the empty label. The other vertex data, including the defaults of the
+// keys, are returned keyed by vertex Idx, typed by their attr.type (int,
+// float64, bool or string). Keys are resolved in document order: when
+// several keys share an attr.name the first one with a value wins.
+func ReadGraphML(r io.Reader, opts *GraphMLOptions) (*Graph, map[int]map[string]interface{}, error) {
+	o := GraphMLOptions{VertexLabel: "label", EdgeLabel: "label", VertexId: "id"}
+	if opts != nil {
+		if opts.VertexLabel != "" {
+			o.VertexLabel = opts.VertexLabel
+		}
+		if opts.EdgeLabel != "" {
+			o.EdgeLabel = opts.EdgeLabel
+		}
+		if opts.VertexId != "" {
+			o.VertexId = opts.VertexId
+		}
+	}
+	dec := xml.NewDecoder(r)
+	errorf := func(format string, args ...interface{}) error {
+		line, _ := dec.InputPos()
+		return fmt.Errorf("goiso: GraphML: error in line %v: %v", line, fmt.Sprintf(format, args...))
+	}
+	type key struct {
+		name, typ, domain, def string
+		hasDef                 bool
+	}
+	type edge struct {
+		src, targ, label string
+		undirected       bool
+	}
+	keys := make(map[string]*key)
+	var keyOrder []string
+	g := NewGraph(100, 100)
+	attrs := make(map[int]map[string]interface{})
+	vids := make(map[string]int)
+	var edges []edge
+	var labels []string
+	var curKey *key
+	var curNode, curEdge map[string]string
+	var curNodeId string
+	var curData string
+	var text []byte
+	inGraph, done := false, false
+	undirected := false
+	attr := func(e xml.StartElement, name string) (string, bool) {
+		for _, a := range e.Attr {
+			if a.Name.Local == name {
+				return a.Value, true
+			}
+		}
+		return "", false
+	}
+	// does the key apply to nodes (or edges)? A key without a for attribute
+	// applies to all elements.
+	applies := func(k *key, forNode bool) bool {
+		if forNode {
+			return k.domain == "node" || k.domain == "all" || k.domain == ""
+		}
+		return k.domain == "edge" || k.domain == "all" || k.domain == ""
+	}
+	// the value (and its key) of the first key in document order which
+	// matches name and has a value. Data given in the element win over the
+	// defaults of the keys.
+	lookup := func(data map[string]string, forNode bool, name string) (string, *key, bool) {
+		for _, id := range keyOrder {
+			if k := keys[id]; (k.name == name || id == name) && applies(k, forNode) {
+				if value, has := data[id]; has {
+					return value, k, true
+				}
+			}
+		}
+		for _, id := range keyOrder {
+			if k := keys[id]; (k.name == name || id == name) && applies(k, forNode) && k.hasDef {
+				return k.def, k, true
+			}
+		}
+		return "", nil, false
+	}
+	for !done {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, errorf("%v", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			text = text[:0]
+			switch t.Name.Local {
+			case "key":
+				id, _ := attr(t, "id")
+				name, has := attr(t, "attr.name")
+				if !has {
+					name = id
+				}
+				typ, _ := attr(t, "attr.type")
+				domain, _ := attr(t, "for")
+				if _, has := keys[id]; has {
+					return nil, nil, errorf("duplicate key id %q", id)
+				}
+				curKey = &key{name: name, typ: typ, domain: domain}
+				keys[id] = curKey
+				keyOrder = append(keyOrder, id)
+			case "graph":
+				if inGraph {
+					return nil, nil, errorf("nested graphs are not supported")
+				}
+				inGraph = true
+				def, _ := attr(t, "edgedefault")
+				undirected = def == "undirected"
+			case "node":
+				if !inGraph {
+					continue
+				}
+				if curNode != nil {
+					return nil, nil, errorf("nested graphs are not supported")
+				}
+				id, has := attr(t, "id")
+				if !has {
+					return nil, nil, errorf("node without an id")
+				}
+				if _, has := vids[id]; has {
+					return nil, nil, errorf("duplicate node id %q", id)
+				}
+				curNode, curNodeId = make(map[string]string), id
+			case "edge":
+				if !inGraph {
+					continue
+				}
+				src, hasSrc := attr(t, "source")
+				targ, hasTarg := attr(t, "target")
+				if !hasSrc || !hasTarg {
+					return nil, nil, errorf("edge without a source or target")
+				}
+				e := edge{src: src, targ: targ, undirected: undirected}
+				if directed, has := attr(t, "directed"); has {
+					e.undirected = directed == "false"
+				}
+				edges = append(edges, e)
+				curEdge = make(map[string]string)
+			case "data":
+				curData, _ = attr(t, "key")
+			case "hyperedge":
+				return nil, nil, errorf("hyperedges are not supported")
+			}
+		case xml.CharData:
+			text = append(text, t...)
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "default":
+				if curKey != nil {
+					curKey.def, curKey.hasDef = string(text), true
+				}
+			case "key":
+				curKey = nil
+			case "data":
+				if curNode != nil {
+					curNode[curData] = string(text)
+				} else if curEdge != nil {
+					curEdge[curData] = string(text)
+				}
+			case "node":
+				if curNode == nil {
+					continue
+				}
+				label, _, _ := lookup(curNode, true, o.VertexLabel)
+				id := len(g.V)
+				if s, _, has := lookup(curNode, true, o.VertexId); has {
+					i, err := strconv.Atoi(s)
+					if err != nil {
+						return nil, nil, errorf("expected an integer %v got %q", o.VertexId, s)
+					}
+					id = i
+				}
+				v := g.AddVertex(id, label)
+				vids[curNodeId] = v.Idx
+				for _, kid := range keyOrder {
+					k := keys[kid]
+					if !applies(k, true) || k.name == o.VertexLabel || k.name == o.VertexId || kid == o.VertexLabel || kid == o.VertexId {
+						continue
+					}
+					if _, has := attrs[v.Idx][k.name]; has {
+						continue
+					}
+					s, src, has := lookup(curNode, true, k.name)
+					if !has {
+						continue
+					}
+					typed, err := graphMLValue(src.typ, s)
+					if err != nil {
+						return nil, nil, errorf("%v", err)
+					}
+					if attrs[v.Idx] == nil {
+						attrs[v.Idx] = make(map[string]interface{})
+					}
+					attrs[v.Idx][k.name] = typed
+				}
+				curNode = nil
+			case "edge":
+				if curEdge == nil {
+					continue
+				}
+				label, _, _ := lookup(curEdge, false, o.EdgeLabel)
+				labels = append(labels, label)
+				curEdge = nil
+			case "graph":
+				done = true
+			}
+			text = text[:0]
+		}
+	}
+	for i, e := range edges {
+		src, has := vids[e.src]
+		if !has {
+			return nil, nil, fmt.Errorf("goiso: GraphML: edge source %q is not a node", e.src)
+		}
+		targ, has := vids[e.targ]
+		if !has {
+			return nil, nil, fmt.Errorf("goiso: GraphML: edge target %q is not a node", e.targ)
+		}
+		g.AddEdge(&g.V[src], &g.V[targ], labels[i])
+		if e.undirected && src != targ {
+			g.AddEdge(&g.V[targ], &g.V[src], labels[i])
+		}
+	}
+	return &g, attrs, nil
+}
+
+// Converts the text of a data element to the Go type of its attr.type.
+func graphMLValue(typ, value string) (interface{}, error) {
+	switch typ {
+	case "boolean":
+		return strconv.ParseBool(value)
+	case "int", "long":
+		return strconv.Atoi(value)
+	case "float", "double":
+		return strconv.ParseFloat(value, 64)
+	}
+	return value, nil
+}
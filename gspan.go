@@ -0,0 +1,186 @@
+package goiso
+
+/*
+  Copyright (c) 2014 Tim Henderson
+  Released under the GNU General Public License version 3.
+
+  This file is part of goiso a wrapper around bliss.
+
+  bliss is free software: you can redistribute it and/or modify
+  it under the terms of the GNU General Public License version 3
+  as published by the Free Software Foundation.
+
+  bliss is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU General Public License for more details.
+
+  You should have received a copy of the GNU General Public License
+  along with goiso.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Reads graphs in the transaction format of gSpan, Gaston and the TU
+// datasets:
+//
+//     t # <graph id> [* <support>]
+//     v <vertex id> <label>
+//     e <src id> <targ id> <label>
+//
+// into a GraphDB so all of the graphs share one label dictionary. The vertex
+// ids become the Vertex Ids. The datasets are undirected: if undirected is
+// true each edge is added in both directions (a loop once). Reading stops at
+// "t # -1". Blank lines and the "x" lines listing the supporting graphs in
+// gSpan's output are skipped.
+func LoadGSpan(r io.Reader, undirected bool) (*GraphDB, error) {
+	db := NewGraphDB()
+	var g *Graph
+	var vids map[int]int
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		errorf := func(format string, args ...interface{}) error {
+			return fmt.Errorf("goiso: gSpan: error in line %v: %v", lineNum, fmt.Sprintf(format, args...))
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		ints := func(names ...string) ([]int, error) {
+			if len(fields) < len(names)+1 {
+				return nil, errorf("expected %v", strings.Join(names, ", "))
+			}
+			values := make([]int, len(names))
+			for i, name := range names {
+				v, err := strconv.Atoi(fields[i+1])
+				if err != nil {
+					return nil, errorf("expected an integer %v got %q", name, fields[i+1])
+				}
+				values[i] = v
+			}
+			return values, nil
+		}
+		label := func(i int) (string, error) {
+			if len(fields) != i+1 {
+				return "", errorf("expected a single label")
+			}
+			return fields[i], nil
+		}
+		switch fields[0] {
+		case "t":
+			if len(fields) < 3 || fields[1] != "#" {
+				return nil, errorf("expected t # <graph id>")
+			}
+			if fields[2] == "-1" {
+				return db, nil
+			}
+			g = db.NewGraph(10, 10)
+			vids = make(map[int]int)
+		case "v":
+			if g == nil {
+				return nil, errorf("vertex before the first graph")
+			}
+			values, err := ints("vertex id")
+			if err != nil {
+				return nil, err
+			}
+			l, err := label(2)
+			if err != nil {
+				return nil, err
+			}
+			if _, has := vids[values[0]]; has {
+				return nil, errorf("duplicate vertex id %v", values[0])
+			}
+			vids[values[0]] = g.AddVertex(values[0], l).Idx
+		case "e":
+			if g == nil {
+				return nil, errorf("edge before the first graph")
+			}
+			values, err := ints("src id", "targ id")
+			if err != nil {
+				return nil, err
+			}
+			l, err := label(3)
+			if err != nil {
+				return nil, err
+			}
+			src, has := vids[values[0]]
+			if !has {
+				return nil, errorf("src %v is not a vertex", values[0])
+			}
+			targ, has := vids[values[1]]
+			if !has {
+				return nil, errorf("targ %v is not a vertex", values[1])
+			}
+			g.AddEdge(&g.V[src], &g.V[targ], l)
+			if undirected && src != targ {
+				g.AddEdge(&g.V[targ], &g.V[src], l)
+			}
+		case "x":
+		default:
+			return nil, errorf("unknown line type %q", fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Writes the subgraph in the transaction format read by LoadGSpan as graph
+// id. The vertices are numbered by their Idx so write the canonical
+// subgraphs (such as the patterns found by a Miner) to get canonical output.
+// If support is not negative it is written in the header as gSpan does. If
+// undirected is true a pair of opposite edges with the same label is written
+// once. Empty labels and labels containing white space can not be read back
+// so they are an error and nothing is written.
+func (sg *SubGraph) WriteGSpan(w io.Writer, id, support int, undirected bool) error {
+	for _, v := range sg.V {
+		if !gspanLabel(sg.G.colors()[v.Color]) {
+			return fmt.Errorf("goiso: gSpan: vertex %v: can not write the label %q", v.Idx, sg.G.colors()[v.Color])
+		}
+	}
+	for _, e := range sg.E {
+		if !gspanLabel(sg.G.colors()[e.Color]) {
+			return fmt.Errorf("goiso: gSpan: edge %v: can not write the label %q", e.Idx, sg.G.colors()[e.Color])
+		}
+	}
+	out := bufio.NewWriter(w)
+	if support >= 0 {
+		fmt.Fprintf(out, "t # %d * %d\n", id, support)
+	} else {
+		fmt.Fprintf(out, "t # %d\n", id)
+	}
+	for _, v := range sg.V {
+		fmt.Fprintf(out, "v %d %s\n", v.Idx, sg.G.colors()[v.Color])
+	}
+	// the unmatched edges of each undirected pair
+	pending := make(map[ColoredArc]int)
+	for _, e := range sg.E {
+		if undirected && e.Src != e.Targ {
+			rev := ColoredArc{Arc{e.Targ, e.Src}, e.Color}
+			if pending[rev] > 0 {
+				pending[rev]--
+				continue
+			}
+			pending[ColoredArc{e.Arc, e.Color}]++
+		}
+		fmt.Fprintf(out, "e %d %d %s\n", e.Src, e.Targ, sg.G.colors()[e.Color])
+	}
+	return out.Flush()
+}
+
+// Can the label be written as a single field of a gSpan line?
+func gspanLabel(label string) bool {
+	return label != "" && strings.IndexFunc(label, unicode.IsSpace) < 0
+}
@@ -0,0 +1,96 @@
+package goiso
+
+/*
+  Copyright (c) 2014 Tim Henderson
+  Released under the GNU General Public License version 3.
+
+  This file is part of goiso a wrapper around bliss.
+
+  bliss is free software: you can redistribute it and/or modify
+  it under the terms of the GNU General Public License version 3
+  as published by the Free Software Foundation.
+
+  bliss is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU General Public License for more details.
+
+  You should have received a copy of the GNU General Public License
+  along with goiso.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Computes an isomorphism from g onto other: a bijection of the vertices and
+// edges which preserves the edges, their direction and all of the labels.
+// Read the returned variables as:
+//
+//   - vmap[vertex.Idx in g] -> Idx of the corresponding vertex in other
+//   - emap[edge.Idx in g] -> Idx of the corresponding edge in other
+//   - ok is false (and the maps are nil) if the graphs are not isomorphic
+//
+// The labels are compared by their strings so the graphs do not need to
+// share color ids (see SetStableLabels). Both graphs are canonized with g's
+// Canonicalizer and the witness is checked before it is returned.
+// Note: this method does finalize both graphs.
+func (g *Graph) Isomorphism(other *Graph) (vmap, emap []int, ok bool) {
+	if len(g.V) != len(other.V) || len(g.E) != len(other.E) {
+		return nil, nil, false
+	}
+	if !g.closed {
+		g.Finalize()
+	}
+	if !other.closed {
+		other.Finalize()
+	}
+	vmap = make([]int, len(g.V))
+	emap = make([]int, len(g.E))
+	if len(g.V) > 0 {
+		canon := g.Canonicalizer()
+		gV, gE := g.stableColors(g.V, g.E)
+		gVord, gEord, _ := canon.CanonicalPermutation(gV, gE)
+		oV, oE := other.stableColors(other.V, other.E)
+		oVord, oEord, _ := canon.CanonicalPermutation(oV, oE)
+		// the vertex (edge) of other at each canonical position
+		oVinv := make([]int, len(oVord))
+		for i, j := range oVord {
+			oVinv[j] = i
+		}
+		oEinv := make([]int, len(oEord))
+		for i, j := range oEord {
+			oEinv[j] = i
+		}
+		for i, j := range gVord {
+			vmap[i] = oVinv[j]
+		}
+		for i, j := range gEord {
+			emap[i] = oEinv[j]
+		}
+	}
+	if !g.isIsomorphism(other, vmap, emap) {
+		return nil, nil, false
+	}
+	return vmap, emap, true
+}
+
+// Checks that vmap and emap are bijections which preserve the labels and the
+// endpoints of every edge.
+func (g *Graph) isIsomorphism(other *Graph, vmap, emap []int) bool {
+	seen := make([]bool, len(other.V))
+	for i, j := range vmap {
+		if seen[j] || g.colors()[g.V[i].Color] != other.colors()[other.V[j].Color] {
+			return false
+		}
+		seen[j] = true
+	}
+	seen = make([]bool, len(other.E))
+	for i, j := range emap {
+		e, f := &g.E[i], &other.E[j]
+		if seen[j] || g.colors()[e.Color] != other.colors()[f.Color] {
+			return false
+		}
+		if vmap[e.Src] != f.Src || vmap[e.Targ] != f.Targ {
+			return false
+		}
+		seen[j] = true
+	}
+	return true
+}
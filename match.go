@@ -0,0 +1,305 @@
+package goiso
+
+/*
+  Copyright (c) 2014 Tim Henderson
+  Released under the GNU General Public License version 3.
+
+  This file is part of goiso a wrapper around bliss.
+
+  bliss is free software: you can redistribute it and/or modify
+  it under the terms of the GNU General Public License version 3
+  as published by the Free Software Foundation.
+
+  bliss is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU General Public License for more details.
+
+  You should have received a copy of the GNU General Public License
+  along with goiso.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+import (
+	"sort"
+)
+
+// Options for a Matcher. A nil *MatchOptions means the zero value: non
+// induced matching without a limit.
+type MatchOptions struct {
+	// Induced embeddings may not have target edges between the matched
+	// vertices other than the images of the pattern's edges. Otherwise the
+	// target may have additional edges.
+	Induced bool
+	// Stop after this many embeddings. Zero means no limit.
+	Limit int
+}
+
+// Finds the embeddings of patterns in a target graph with a VF2 style
+// backtracking search. An embedding maps each pattern vertex onto a distinct
+// target vertex such that the vertex labels match and every pattern edge has
+// a target edge with the same label and direction. Labels are compared by
+// their strings so the pattern may come from a different graph than the
+// target. A Matcher may be reused for many patterns.
+type Matcher struct {
+	target *Graph
+	opts   MatchOptions
+	// the target edge colors between each ordered pair of vertices, sorted
+	pairs map[Arc][]int
+	// the distinct out and in neighbors of each target vertex
+	out, in [][]int
+	// the target vertices of each color
+	byColor map[int][]int
+}
+
+// Constructs a Matcher over the target graph. opts may be nil.
+func NewMatcher(target *Graph, opts *MatchOptions) *Matcher {
+	m := &Matcher{
+		target:  target,
+		byColor: make(map[int][]int),
+	}
+	if opts != nil {
+		m.opts = *opts
+	}
+	m.pairs, m.out, m.in = adjacency(len(target.V), target.E, func(color int) int { return color })
+	for _, v := range target.V {
+		m.byColor[v.Color] = append(m.byColor[v.Color], v.Idx)
+	}
+	return m
+}
+
+// Builds the sorted edge colors between each ordered pair of vertices and the
+// distinct neighbors of each vertex. color translates the edge colors.
+func adjacency(lenV int, E Edges, color func(int) int) (pairs map[Arc][]int, out, in [][]int) {
+	pairs = make(map[Arc][]int, len(E))
+	out = make([][]int, lenV)
+	in = make([][]int, lenV)
+	for _, e := range E {
+		colors, has := pairs[e.Arc]
+		if !has {
+			out[e.Src] = append(out[e.Src], e.Targ)
+			in[e.Targ] = append(in[e.Targ], e.Src)
+		}
+		pairs[e.Arc] = append(colors, color(e.Color))
+	}
+	for _, colors := range pairs {
+		sort.Ints(colors)
+	}
+	return pairs, out, in
+}
+
+// Finds the embeddings of the subgraph in the target. found is called with
+// each embedding, read it as:
+//
+//     embedding[pattern vertex Idx] -> target vertex Idx
+//
+// The embedding slice belongs to the caller. Return false from found to stop
+// the search early. found may be nil. Returns the number of embeddings
+// found.
+func (m *Matcher) Match(pattern *SubGraph, found func(embedding []int) bool) int {
+	return m.match(pattern.V, pattern.E, pattern.G.colors(), found)
+}
+
+// The same as Match but the pattern is a (small) Graph.
+func (m *Matcher) MatchGraph(pattern *Graph, found func(embedding []int) bool) int {
+	return m.match(pattern.V, pattern.E, pattern.colors(), found)
+}
+
+// The state of a single search.
+type matchState struct {
+	*Matcher
+	colors []int // the target color of each pattern vertex, -1 if missing
+	pairs  map[Arc][]int
+	out    [][]int
+	in     [][]int
+	order  []int // the pattern vertices in the order they are matched
+	// the earlier matched pattern neighbor of order[d] the candidates are
+	// drawn from, -1 if none. outgoing if the edge is parent -> order[d]
+	parent   []int
+	outgoing []bool
+	f        []int // pattern vertex -> target vertex, -1 if unmatched
+	rev      []int // target vertex -> pattern vertex, -1 if unmatched
+	count    int
+	found    func([]int) bool
+}
+
+func (m *Matcher) match(V Vertices, E Edges, labels []string, found func([]int) bool) int {
+	s := &matchState{
+		Matcher: m,
+		colors:  make([]int, len(V)),
+		f:       make([]int, len(V)),
+		rev:     make([]int, len(m.target.V)),
+		found:   found,
+	}
+	color := func(c int) int {
+		if tc, has := m.target.Labels[labels[c]]; has {
+			return tc
+		}
+		return -1
+	}
+	for i, v := range V {
+		s.colors[i] = color(v.Color)
+		s.f[i] = -1
+	}
+	for i := range s.rev {
+		s.rev[i] = -1
+	}
+	s.pairs, s.out, s.in = adjacency(len(V), E, color)
+	s.plan()
+	s.extend(0)
+	return s.count
+}
+
+// Orders the pattern vertices. Each vertex is preferably adjacent to one
+// matched before it so its candidates are the neighbors of that vertex's
+// image. Otherwise the vertex with the rarest label goes next.
+func (s *matchState) plan() {
+	n := len(s.colors)
+	ordered := make([]bool, n)
+	s.order = make([]int, 0, n)
+	s.parent = make([]int, 0, n)
+	s.outgoing = make([]bool, 0, n)
+	for len(s.order) < n {
+		next, parent, outgoing := -1, -1, false
+		bestConn := -1
+		for p := 0; p < n; p++ {
+			if ordered[p] {
+				continue
+			}
+			conn := 0
+			par, out := -1, false
+			for _, q := range s.in[p] {
+				if ordered[q] {
+					conn++
+					par, out = q, true
+				}
+			}
+			for _, q := range s.out[p] {
+				if ordered[q] {
+					conn++
+					if par < 0 {
+						par, out = q, false
+					}
+				}
+			}
+			better := conn > bestConn
+			if conn == bestConn && conn == 0 {
+				better = len(s.byColor[s.colors[p]]) < len(s.byColor[s.colors[next]])
+			}
+			if better {
+				next, parent, outgoing, bestConn = p, par, out, conn
+			}
+		}
+		ordered[next] = true
+		s.order = append(s.order, next)
+		s.parent = append(s.parent, parent)
+		s.outgoing = append(s.outgoing, outgoing)
+	}
+}
+
+// Matches the pattern vertex at depth d of the order. Returns false once the
+// search should stop.
+func (s *matchState) extend(d int) bool {
+	if d == len(s.order) {
+		s.count++
+		if s.found != nil {
+			embedding := make([]int, len(s.f))
+			copy(embedding, s.f)
+			if !s.found(embedding) {
+				return false
+			}
+		}
+		return s.opts.Limit <= 0 || s.count < s.opts.Limit
+	}
+	p := s.order[d]
+	var cands []int
+	if q := s.parent[d]; q < 0 {
+		cands = s.byColor[s.colors[p]]
+	} else if s.outgoing[d] {
+		cands = s.Matcher.out[s.f[q]]
+	} else {
+		cands = s.Matcher.in[s.f[q]]
+	}
+	for _, t := range cands {
+		if !s.feasible(p, t) {
+			continue
+		}
+		s.f[p] = t
+		s.rev[t] = p
+		more := s.extend(d + 1)
+		s.f[p] = -1
+		s.rev[t] = -1
+		if !more {
+			return false
+		}
+	}
+	return true
+}
+
+// Can pattern vertex p be mapped onto target vertex t given the vertices
+// matched so far?
+func (s *matchState) feasible(p, t int) bool {
+	if s.rev[t] >= 0 || s.target.V[t].Color != s.colors[p] {
+		return false
+	}
+	if len(s.Matcher.out[t]) < len(s.out[p]) || len(s.Matcher.in[t]) < len(s.in[p]) {
+		return false
+	}
+	if !s.edgesMatch(p, p, t, t) {
+		return false
+	}
+	for _, q := range s.out[p] {
+		if s.f[q] >= 0 && !s.edgesMatch(p, q, t, s.f[q]) {
+			return false
+		}
+	}
+	for _, q := range s.in[p] {
+		if s.f[q] >= 0 && !s.edgesMatch(q, p, s.f[q], t) {
+			return false
+		}
+	}
+	if s.opts.Induced {
+		// no target edges between t and matched vertices beyond the pattern's
+		for _, u := range s.Matcher.out[t] {
+			if q := s.rev[u]; q >= 0 && !s.edgesMatch(p, q, t, u) {
+				return false
+			}
+		}
+		for _, u := range s.Matcher.in[t] {
+			if q := s.rev[u]; q >= 0 && !s.edgesMatch(q, p, u, t) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Do the pattern edges p -> q fit the target edges a -> b? When matching
+// induced subgraphs the labels must be the same multiset otherwise the
+// target may have more.
+func (s *matchState) edgesMatch(p, q, a, b int) bool {
+	pattern := s.pairs[Arc{p, q}]
+	target := s.Matcher.pairs[Arc{a, b}]
+	if s.opts.Induced {
+		if len(pattern) != len(target) {
+			return false
+		}
+		for i := range pattern {
+			if pattern[i] != target[i] {
+				return false
+			}
+		}
+		return true
+	}
+	// both are sorted: check pattern is a sub-multiset of target
+	j := 0
+	for _, c := range pattern {
+		for j < len(target) && target[j] < c {
+			j++
+		}
+		if j >= len(target) || target[j] != c {
+			return false
+		}
+		j++
+	}
+	return true
+}
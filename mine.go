@@ -0,0 +1,212 @@
+package goiso
+
+/*
+  Copyright (c) 2014 Tim Henderson
+  Released under the GNU General Public License version 3.
+
+  This file is part of goiso a wrapper around bliss.
+
+  bliss is free software: you can redistribute it and/or modify
+  it under the terms of the GNU General Public License version 3
+  as published by the Free Software Foundation.
+
+  bliss is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU General Public License for more details.
+
+  You should have received a copy of the GNU General Public License
+  along with goiso.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// Options for a Miner. A nil *MinerOptions means the zero value: every
+// connected pattern is reported.
+type MinerOptions struct {
+	// Patterns with less support are pruned along with their extensions.
+	MinSupport int
+	// The maximum number of edges in a pattern. Zero means no limit.
+	MaxEdges int
+	// The maximum number of vertices in a pattern. Zero means no limit.
+	MaxVertices int
+	// How support is computed, see Support. Only the anti-monotone measures
+	// (MNI, MIS and HarmfulOverlap) make the pruning exact.
+	Measure SupportMeasure
+}
+
+// Mines the frequent connected subgraphs of a Graph. Patterns are grown an
+// edge at a time with SubGraph.EdgeExtend starting from the single vertex
+// subgraphs (Graph.VertexSubGraph). Isomorphic patterns are recognized by
+// their ShortLabel so each pattern is explored once no matter how many ways
+// it can be grown. Each embedding found is a distinct subgraph of the graph so
+// symmetric copies are not counted twice.
+type Miner struct {
+	G    *Graph
+	opts MinerOptions
+	seen map[string]bool
+}
+
+// Constructs a Miner over g. opts may be nil.
+func NewMiner(g *Graph, opts *MinerOptions) *Miner {
+	m := &Miner{G: g}
+	if opts != nil {
+		m.opts = *opts
+	}
+	return m
+}
+
+// Mine the graph. found is called with each frequent pattern (in canonical
+// form), its support and its embeddings. Read each embedding as:
+//
+//     embedding[pattern vertex Idx] -> graph vertex Idx
+//
+// Patterns are reported depth first: a pattern is always reported before its
+// extensions. Return false from found to stop mining.
+func (m *Miner) Mine(found func(pattern *SubGraph, support int, embeddings [][]int) bool) {
+	m.seen = make(map[string]bool)
+	seeds := newPatternSet()
+	for i := range m.G.V {
+		sg, _ := m.G.VertexSubGraph(i)
+		seeds.add(sg, embeddingKey(sg, nil))
+	}
+	m.explore(seeds, found)
+}
+
+// The embeddings of the patterns found while extending a pattern grouped by
+// their labels. keys holds the embeddingKey of every embedding added.
+type patternSet struct {
+	groups map[string][]*SubGraph
+	keys   map[string]bool
+}
+
+func newPatternSet() *patternSet {
+	return &patternSet{
+		groups: make(map[string][]*SubGraph),
+		keys:   make(map[string]bool),
+	}
+}
+
+func (s *patternSet) add(sg *SubGraph, key string) {
+	label := string(sg.ShortLabel())
+	s.keys[key] = true
+	s.groups[label] = append(s.groups[label], sg)
+}
+
+// Reports and extends the unseen frequent patterns in the set. Returns false
+// once mining should stop.
+func (m *Miner) explore(s *patternSet, found func(*SubGraph, int, [][]int) bool) bool {
+	labels := make([]string, 0, len(s.groups))
+	for label := range s.groups {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		if m.seen[label] {
+			continue
+		}
+		// every embedding of the pattern extends an embedding of the pattern
+		// it was grown from so the group is complete the first time it is
+		// seen.
+		m.seen[label] = true
+		group := s.groups[label]
+		embeddings := make([][]int, 0, len(group))
+		for _, sg := range group {
+			embedding := make([]int, len(sg.V))
+			for i := range sg.V {
+				embedding[i] = sg.V[i].Id
+			}
+			embeddings = append(embeddings, embedding)
+		}
+		pattern := group[0]
+		support := Support(pattern, embeddings, m.opts.Measure)
+		if support < m.opts.MinSupport {
+			continue
+		}
+		if !found(pattern, support, embeddings) {
+			return false
+		}
+		if m.opts.MaxEdges > 0 && len(pattern.E) >= m.opts.MaxEdges {
+			continue
+		}
+		if !m.explore(m.extensions(group), found) {
+			return false
+		}
+	}
+	return true
+}
+
+// Grows each embedding in the group by every edge of the graph adjacent to
+// it.
+func (m *Miner) extensions(group []*SubGraph) *patternSet {
+	s := newPatternSet()
+	for _, sg := range group {
+		full := m.opts.MaxVertices > 0 && len(sg.V) >= m.opts.MaxVertices
+		for _, v := range sg.V {
+			for _, edges := range [][]*Edge{m.G.Kids[v.Id], m.G.Parents[v.Id]} {
+				for _, e := range edges {
+					if sg.HasEdge(ColoredArc{e.Arc, e.Color}) {
+						continue
+					}
+					if full && !(sg.HasVertex(e.Src) && sg.HasVertex(e.Targ)) {
+						continue
+					}
+					key := embeddingKey(sg, e)
+					if s.keys[key] {
+						continue
+					}
+					nsg, _ := sg.EdgeExtend(e)
+					s.add(nsg, key)
+				}
+			}
+		}
+	}
+	return s
+}
+
+// Identifies the subgraph of the graph made of sg plus the (optional) edge
+// regardless of the order of its vertices and edges.
+func embeddingKey(sg *SubGraph, edge *Edge) string {
+	vids := make([]int, 0, len(sg.V)+2)
+	for _, v := range sg.V {
+		vids = append(vids, v.Id)
+	}
+	arcs := make([]ColoredArc, 0, len(sg.E)+1)
+	for _, e := range sg.E {
+		arcs = append(arcs, ColoredArc{Arc{sg.V[e.Src].Id, sg.V[e.Targ].Id}, e.Color})
+	}
+	if edge != nil {
+		if !sg.HasVertex(edge.Src) {
+			vids = append(vids, edge.Src)
+		}
+		if !sg.HasVertex(edge.Targ) && edge.Src != edge.Targ {
+			vids = append(vids, edge.Targ)
+		}
+		arcs = append(arcs, ColoredArc{edge.Arc, edge.Color})
+	}
+	sort.Ints(vids)
+	sort.Slice(arcs, func(i, j int) bool {
+		a, b := arcs[i], arcs[j]
+		if a.Src != b.Src {
+			return a.Src < b.Src
+		}
+		if a.Targ != b.Targ {
+			return a.Targ < b.Targ
+		}
+		return a.Color < b.Color
+	})
+	key := make([]byte, 0, 4*len(vids)+12*len(arcs))
+	key = binary.AppendUvarint(key, uint64(len(vids)))
+	for _, vid := range vids {
+		key = binary.AppendUvarint(key, uint64(vid))
+	}
+	for _, a := range arcs {
+		key = binary.AppendUvarint(key, uint64(a.Src))
+		key = binary.AppendUvarint(key, uint64(a.Targ))
+		key = binary.AppendUvarint(key, uint64(a.Color))
+	}
+	return string(key)
+}
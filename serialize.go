@@ -0,0 +1,738 @@
+package goiso
+
+/*
+  Copyright (c) 2014 Tim Henderson
+  Released under the GNU General Public License version 3.
+
+  This file is part of goiso a wrapper around bliss.
+
+  bliss is free software: you can redistribute it and/or modify
+  it under the terms of the GNU General Public License version 3
+  as published by the Free Software Foundation.
+
+  bliss is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU General Public License for more details.
+
+  You should have received a copy of the GNU General Public License
+  along with goiso.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sort"
+)
+
+const (
+	serialMarkV1 = 0xaaaaaaaa
+	serialMarkV2 = 0xab
+	serialV2     = 2
+	// the v2 flags
+	serialLabels = 1 << 0
+
+	graphMark    = 0xac
+	graphVersion = 1
+	// the graph flags
+	graphClosed = 1 << 0
+	graphCanon  = 1 << 1
+	graphStable = 1 << 2
+)
+
+// Encodes the subgraph in the v2 serialization format:
+//
+//     (mark 0xab : 1)(version 2 : 1)(flags : 1)
+//     (vertex count)(edge count)
+//     [vertex (id)(color)]*
+//     [edge (src idx)(targ idx)(color)]*
+//     [if flags&1: (label count)[(color)(length)(label bytes)]*]
+//     (crc32 of the above : 4 little endian)
+//
+// All numbers in parentheses without a size are unsigned varints. The vertex
+// id is its Idx in the parent Graph. If withLabels is true the label of
+// every color used is embedded so the subgraph can be decoded without the
+// parent graph's color table. Vertices and edges are in the (canonical)
+// order of the subgraph.
+func (sg *SubGraph) Encode(withLabels bool) []byte {
+	buf := make([]byte, 3, 3+2*binary.MaxVarintLen32+len(sg.V)*4+len(sg.E)*6+4)
+	buf[0], buf[1] = serialMarkV2, serialV2
+	if withLabels {
+		buf[2] = serialLabels
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(sg.V)))
+	buf = binary.AppendUvarint(buf, uint64(len(sg.E)))
+	colors := make(map[int]bool)
+	for _, v := range sg.V {
+		buf = binary.AppendUvarint(buf, uint64(v.Id))
+		buf = binary.AppendUvarint(buf, uint64(v.Color))
+		colors[v.Color] = true
+	}
+	for _, e := range sg.E {
+		buf = binary.AppendUvarint(buf, uint64(e.Src))
+		buf = binary.AppendUvarint(buf, uint64(e.Targ))
+		buf = binary.AppendUvarint(buf, uint64(e.Color))
+		colors[e.Color] = true
+	}
+	if withLabels {
+		cids := make([]int, 0, len(colors))
+		for cid := range colors {
+			cids = append(cids, cid)
+		}
+		sort.Ints(cids)
+		buf = binary.AppendUvarint(buf, uint64(len(cids)))
+		for _, cid := range cids {
+			label := sg.G.colors()[cid]
+			buf = binary.AppendUvarint(buf, uint64(cid))
+			buf = binary.AppendUvarint(buf, uint64(len(label)))
+			buf = append(buf, label...)
+		}
+	}
+	return binary.LittleEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf))
+}
+
+// Decodes a subgraph written by Encode (v2) or Serialize (v1) returning an
+// error for malformed or corrupted input.
+//
+// When the data embeds its labels the colors are translated to g's color
+// table by label, so the data may come from a different Graph with the same
+// vertices, and g may be nil. Without a parent graph a new Graph is built
+// from the subgraph alone: its vertices are the subgraph's (in order) and
+// their Ids are the vertex ids in the data (the Idx in the original graph).
+// The v1 format and v2 without labels need g.
+func DecodeSubGraph(g *Graph, data []byte) (*SubGraph, error) {
+	if len(data) >= 4 && binary.LittleEndian.Uint32(data) == serialMarkV1 {
+		return decodeV1(g, data)
+	}
+	if len(data) < 3 || data[0] != serialMarkV2 {
+		return nil, errors.New("goiso: subgraph: not a serialized subgraph")
+	}
+	if data[1] != serialV2 {
+		return nil, fmt.Errorf("goiso: subgraph: unknown version %v", data[1])
+	}
+	if len(data) < 7 {
+		return nil, errors.New("goiso: subgraph: truncated")
+	}
+	body, sum := data[:len(data)-4], binary.LittleEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return nil, errors.New("goiso: subgraph: checksum mismatch")
+	}
+	flags := body[2]
+	r := &varintReader{data: body[3:]}
+	lenV, lenE := r.count(), r.count()
+	V := make([]Vertex, lenV)
+	E := make([]Edge, lenE)
+	for i := range V {
+		V[i] = Vertex{Idx: i, Id: r.int(), Color: r.int()}
+	}
+	for i := range E {
+		E[i] = Edge{Arc: Arc{Src: r.int(), Targ: r.int()}, Idx: i, Color: r.int()}
+	}
+	var labels map[int]string
+	if flags&serialLabels != 0 {
+		n := r.count()
+		labels = make(map[int]string, n)
+		for i := 0; i < n; i++ {
+			cid := r.int()
+			labels[cid] = string(r.bytes(r.count()))
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if len(r.data) != 0 {
+		return nil, errors.New("goiso: subgraph: trailing bytes")
+	}
+	for _, e := range E {
+		if e.Src >= len(V) || e.Targ >= len(V) {
+			return nil, fmt.Errorf("goiso: subgraph: edge %v out of range", e.Idx)
+		}
+	}
+	if labels != nil && g == nil {
+		return standaloneSubGraph(V, E, labels)
+	} else if labels != nil {
+		// translate the colors to g's
+		translate := func(cid int) (int, error) {
+			label, has := labels[cid]
+			if !has {
+				return 0, fmt.Errorf("goiso: subgraph: color %v has no label", cid)
+			}
+			if gcid, has := g.Labels[label]; has {
+				return gcid, nil
+			}
+			return 0, fmt.Errorf("goiso: subgraph: label %q is not in the graph", label)
+		}
+		var err error
+		for i := range V {
+			if V[i].Color, err = translate(V[i].Color); err != nil {
+				return nil, err
+			}
+		}
+		for i := range E {
+			if E[i].Color, err = translate(E[i].Color); err != nil {
+				return nil, err
+			}
+		}
+	} else if g == nil {
+		return nil, errors.New("goiso: subgraph: the data has no labels so it needs its graph")
+	}
+	if err := checkSubGraph(g, V, E); err != nil {
+		return nil, err
+	}
+	return identitySubGraph(g, V, E), nil
+}
+
+// Decodes the v1 format, see Serialize.
+func decodeV1(g *Graph, data []byte) (*SubGraph, error) {
+	if g == nil {
+		return nil, errors.New("goiso: subgraph: the v1 format needs its graph")
+	}
+	if len(data) < 12 {
+		return nil, errors.New("goiso: subgraph: truncated")
+	}
+	lenV := uint64(binary.LittleEndian.Uint32(data[4:8]))
+	lenE := uint64(binary.LittleEndian.Uint32(data[8:12]))
+	if uint64(len(data)) != 12+4*lenV+12*lenE {
+		return nil, fmt.Errorf("goiso: subgraph: expected %v bytes got %v", 12+4*lenV+12*lenE, len(data))
+	}
+	V := make([]Vertex, lenV)
+	E := make([]Edge, lenE)
+	off := 12
+	for i := range V {
+		id := int(binary.LittleEndian.Uint32(data[off:]))
+		if id >= len(g.V) {
+			return nil, fmt.Errorf("goiso: subgraph: vertex %v is not in the graph", id)
+		}
+		V[i] = Vertex{Idx: i, Id: id, Color: g.V[id].Color}
+		off += 4
+	}
+	for i := range E {
+		E[i] = Edge{
+			Arc: Arc{
+				Src:  int(binary.LittleEndian.Uint32(data[off:])),
+				Targ: int(binary.LittleEndian.Uint32(data[off+4:])),
+			},
+			Idx:   i,
+			Color: int(binary.LittleEndian.Uint32(data[off+8:])),
+		}
+		off += 12
+		if E[i].Src >= len(V) || E[i].Targ >= len(V) {
+			return nil, fmt.Errorf("goiso: subgraph: edge %v out of range", i)
+		}
+	}
+	if err := checkSubGraph(g, V, E); err != nil {
+		return nil, err
+	}
+	return identitySubGraph(g, V, E), nil
+}
+
+// Checks the vertices and edges refer to g.
+func checkSubGraph(g *Graph, V Vertices, E Edges) error {
+	seen := make(map[int]bool, len(V))
+	for _, v := range V {
+		if v.Id >= len(g.V) {
+			return fmt.Errorf("goiso: subgraph: vertex %v is not in the graph", v.Id)
+		}
+		if seen[v.Id] {
+			return fmt.Errorf("goiso: subgraph: vertex %v appears twice", v.Id)
+		}
+		seen[v.Id] = true
+		if g.V[v.Id].Color != v.Color {
+			return fmt.Errorf("goiso: subgraph: vertex %v has the wrong label", v.Id)
+		}
+	}
+	for _, e := range E {
+		if e.Color >= len(g.colors()) {
+			return fmt.Errorf("goiso: subgraph: edge %v has an unknown color", e.Idx)
+		}
+	}
+	return nil
+}
+
+// Builds a Graph of the subgraph alone and returns the subgraph of all of
+// it. The graph's vertices keep the ids in V as their Ids.
+func standaloneSubGraph(V Vertices, E Edges, labels map[int]string) (*SubGraph, error) {
+	label := func(cid int) (string, error) {
+		if l, has := labels[cid]; has {
+			return l, nil
+		}
+		return "", fmt.Errorf("goiso: subgraph: color %v has no label", cid)
+	}
+	g := NewGraph(len(V), len(E))
+	for i := range V {
+		l, err := label(V[i].Color)
+		if err != nil {
+			return nil, err
+		}
+		g.AddVertex(V[i].Id, l)
+		V[i].Id, V[i].Color = i, g.V[i].Color
+	}
+	for i := range E {
+		l, err := label(E[i].Color)
+		if err != nil {
+			return nil, err
+		}
+		E[i].Color = g.AddEdge(&g.V[E[i].Src], &g.V[E[i].Targ], l).Color
+	}
+	return identitySubGraph(&g, V, E), nil
+}
+
+// The subgraph of V and E in their current order.
+func identitySubGraph(g *Graph, V Vertices, E Edges) *SubGraph {
+	vord := make([]int, len(V))
+	for i := range vord {
+		vord[i] = i
+	}
+	eord := make([]int, len(E))
+	for i := range eord {
+		eord[i] = i
+	}
+	return permuteSubGraph(g, V, E, vord, eord)
+}
+
+// Reads the varints of the v2 format. The first error sticks and later
+// reads return zeros.
+type varintReader struct {
+	data []byte
+	err  error
+}
+
+func (r *varintReader) uint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	x, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = errors.New("goiso: subgraph: truncated or overflowing varint")
+		return 0
+	}
+	r.data = r.data[n:]
+	return x
+}
+
+func (r *varintReader) int() int {
+	x := r.uint()
+	if x > uint64(maxInt) {
+		r.fail("goiso: subgraph: %v is too large", x)
+		return 0
+	}
+	return int(x)
+}
+
+// Reads a count of things each taking at least a byte so counts larger than
+// the data left (which would allocate absurdly) are errors.
+func (r *varintReader) count() int {
+	x := r.int()
+	if x > len(r.data) {
+		r.fail("goiso: subgraph: count %v exceeds the data", x)
+		return 0
+	}
+	return x
+}
+
+func (r *varintReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *varintReader) fail(format string, args ...interface{}) {
+	if r.err == nil {
+		r.err = fmt.Errorf(format, args...)
+	}
+}
+
+const maxInt = int(^uint(0) >> 1)
+
+// Encodes the whole graph, see WriteTo for the format. It implements
+// encoding.BinaryMarshaler.
+func (g *Graph) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := g.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Replaces g with the graph in data (see WriteTo). It implements
+// encoding.BinaryUnmarshaler.
+func (g *Graph) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	br := bufio.NewReaderSize(r, 1<<16)
+	ng, err := readGraph(&graphDecoder{r: br, checksum: true})
+	if err != nil {
+		return err
+	}
+	if br.Buffered() != 0 || r.Len() != 0 {
+		return errors.New("goiso: graph: trailing bytes")
+	}
+	*g = *ng
+	return nil
+}
+
+// Writes the graph to w in a binary format:
+//
+//     (mark 0xac : 1)(version 1 : 1)(flags : 1)
+//     (color count)[(length)(label bytes)]*
+//     (vertex count)[(id : signed)(color)]*
+//     (edge count)[(src idx)(targ idx)(color)]*
+//     (crc32 of the above : 4 little endian)
+//
+// All numbers in parentheses without a size are varints. The flags record
+// whether the graph is finalized, canonical and in stable label mode. The
+// colors keep their ids and the vertices and edges their order so
+// SubGraphs serialized against the graph can be decoded against the graph
+// read back. The Canonicalizer and GraphDB are not saved. It implements
+// io.WriterTo.
+func (g *Graph) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	out := bufio.NewWriter(cw)
+	crc := crc32.NewIEEE()
+	enc := &graphEncoder{w: io.MultiWriter(out, crc)}
+	var flags byte
+	if g.closed {
+		flags |= graphClosed
+	}
+	if g.canon {
+		flags |= graphCanon
+	}
+	if g.stable {
+		flags |= graphStable
+	}
+	enc.write([]byte{graphMark, graphVersion, flags})
+	enc.uint(uint64(len(g.colors())))
+	for _, label := range g.colors() {
+		enc.uint(uint64(len(label)))
+		enc.write([]byte(label))
+	}
+	enc.uint(uint64(len(g.V)))
+	for _, v := range g.V {
+		enc.int(int64(v.Id))
+		enc.uint(uint64(v.Color))
+	}
+	enc.uint(uint64(len(g.E)))
+	for _, e := range g.E {
+		enc.uint(uint64(e.Src))
+		enc.uint(uint64(e.Targ))
+		enc.uint(uint64(e.Color))
+	}
+	if enc.err == nil {
+		_, enc.err = out.Write(binary.LittleEndian.AppendUint32(nil, crc.Sum32()))
+	}
+	if enc.err == nil {
+		enc.err = out.Flush()
+	}
+	return cw.n, enc.err
+}
+
+// Reads a graph written by Graph.WriteTo (or MarshalBinary). Kids, Parents
+// and the color frequencies are rebuilt and a graph saved finalized is
+// finalized again. r is read up to the end of the graph only if it is an
+// io.ByteReader, otherwise it may be read further. A *bufio.Reader is the
+// fastest to read from.
+func ReadGraph(r io.Reader) (*Graph, error) {
+	switch br := r.(type) {
+	case *bufio.Reader:
+		return readGraph(&graphDecoder{r: br, checksum: true})
+	case io.ByteReader:
+		return readGraph(&graphDecoder{br: br, in: r, checksum: true})
+	}
+	return readGraph(&graphDecoder{r: bufio.NewReaderSize(r, 1<<16), checksum: true})
+}
+
+func readGraph(dec *graphDecoder) (*Graph, error) {
+	header := dec.bytes(3)
+	if dec.err != nil {
+		return nil, dec.err
+	}
+	if header[0] != graphMark {
+		return nil, errors.New("goiso: graph: not a serialized graph")
+	}
+	if header[1] != graphVersion {
+		return nil, fmt.Errorf("goiso: graph: unknown version %v", header[1])
+	}
+	flags := header[2]
+	nColors := dec.count()
+	g := NewGraph(0, 0)
+	for i := 0; i < nColors && dec.err == nil; i++ {
+		label := string(dec.bytes(dec.count()))
+		if _, has := g.Labels[label]; has {
+			return nil, fmt.Errorf("goiso: graph: duplicate label %q", label)
+		}
+		g.Labels[label] = len(g.Colors)
+		g.Colors = append(g.Colors, label)
+	}
+	g.colorFreq = make([]int, len(g.Colors))
+	color := func() int {
+		c := dec.int()
+		if c >= len(g.Colors) && dec.err == nil {
+			dec.err = fmt.Errorf("goiso: graph: unknown color %v", c)
+			return 0
+		}
+		return c
+	}
+	nV := dec.count()
+	g.V = make([]Vertex, 0, capHint(nV))
+	for i := 0; i < nV && dec.err == nil; i++ {
+		v := Vertex{Idx: i, Id: int(dec.signed()), Color: color()}
+		if dec.err != nil {
+			break
+		}
+		g.V = append(g.V, v)
+		g.colorFreq[v.Color] += 1
+	}
+	nE := dec.count()
+	g.E = make([]Edge, 0, capHint(nE))
+	for i := 0; i < nE && dec.err == nil; i++ {
+		e := Edge{Arc: Arc{Src: dec.int(), Targ: dec.int()}, Idx: i, Color: color()}
+		if (e.Src >= len(g.V) || e.Targ >= len(g.V)) && dec.err == nil {
+			dec.err = fmt.Errorf("goiso: graph: edge %v out of range", i)
+		}
+		if dec.err != nil {
+			break
+		}
+		g.E = append(g.E, e)
+		g.colorFreq[e.Color] += 1
+	}
+	if dec.err != nil {
+		return nil, dec.err
+	}
+	dec.flush()
+	sum := dec.crc
+	dec.checksum = false
+	stored := dec.bytes(4)
+	if dec.err != nil {
+		return nil, dec.err
+	}
+	if binary.LittleEndian.Uint32(stored) != sum {
+		return nil, errors.New("goiso: graph: checksum mismatch")
+	}
+	g.rebuildAdjacency()
+	g.stable = flags&graphStable != 0
+	if flags&graphClosed != 0 {
+		g.Finalize()
+	}
+	g.canon = flags&graphCanon != 0
+	return &g, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// Writes varints. The first error sticks.
+type graphEncoder struct {
+	w   io.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (e *graphEncoder) write(p []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(p)
+	}
+}
+
+func (e *graphEncoder) uint(x uint64) {
+	e.write(e.buf[:binary.PutUvarint(e.buf[:], x)])
+}
+
+func (e *graphEncoder) int(x int64) {
+	e.write(e.buf[:binary.PutVarint(e.buf[:], x)])
+}
+
+// Reads varints and byte strings checksumming what it reads (while checksum
+// is true). From a bufio.Reader the varints are decoded in place from a
+// window on the reader's buffer. The window is checksummed and discarded
+// from the reader all at once when the next varint may not fit in it (or
+// before reading a byte string) so the checksum is updated once per buffer
+// full rather than once per varint. Otherwise the varints are read one byte
+// at a time from br so nothing past the graph is read. The first error
+// sticks and later reads return zeros.
+type graphDecoder struct {
+	r        *bufio.Reader
+	window   []byte
+	pos      int // the bytes of the window before pos are decoded
+	br       io.ByteReader
+	in       io.Reader // the same reader as br
+	scratch  [binary.MaxVarintLen64]byte
+	crc      uint32
+	checksum bool
+	err      error
+}
+
+// Checksums the decoded bytes of the window and discards them from the
+// reader.
+func (d *graphDecoder) flush() {
+	if d.r == nil {
+		return
+	}
+	if d.checksum {
+		d.crc = crc32.Update(d.crc, crc32.IEEETable, d.window[:d.pos])
+	}
+	d.r.Discard(d.pos)
+	d.window, d.pos = nil, 0
+}
+
+// The undecoded bytes of the window. There are at least
+// binary.MaxVarintLen64 of them unless the input ends first. It only blocks
+// on the reader for as many bytes as a varint may need.
+func (d *graphDecoder) next() []byte {
+	if len(d.window)-d.pos >= binary.MaxVarintLen64 {
+		return d.window[d.pos:]
+	}
+	d.flush()
+	_, err := d.r.Peek(binary.MaxVarintLen64)
+	d.window, _ = d.r.Peek(d.r.Buffered())
+	if len(d.window) == 0 {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		d.fail(err)
+	}
+	return d.window
+}
+
+// The bytes of the next varint.
+func (d *graphDecoder) varint() []byte {
+	if d.err != nil {
+		return nil
+	}
+	if d.r != nil {
+		buf := d.next()
+		for i := 0; i < len(buf) && i < binary.MaxVarintLen64; i++ {
+			if buf[i] < 0x80 {
+				d.pos += i + 1
+				return buf[:i+1]
+			}
+		}
+		if len(buf) < binary.MaxVarintLen64 {
+			d.fail(io.ErrUnexpectedEOF)
+		} else {
+			d.fail(errors.New("varint overflows a 64-bit integer"))
+		}
+		return nil
+	}
+	for i := range d.scratch {
+		c, err := d.br.ReadByte()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			d.fail(err)
+			return nil
+		}
+		d.scratch[i] = c
+		if c < 0x80 {
+			if d.checksum {
+				d.crc = crc32.Update(d.crc, crc32.IEEETable, d.scratch[:i+1])
+			}
+			return d.scratch[:i+1]
+		}
+	}
+	d.fail(errors.New("varint overflows a 64-bit integer"))
+	return nil
+}
+
+func (d *graphDecoder) fail(err error) {
+	if d.err == nil {
+		d.err = fmt.Errorf("goiso: graph: %v", err)
+	}
+}
+
+func (d *graphDecoder) uint() uint64 {
+	raw := d.varint()
+	if d.err != nil {
+		return 0
+	}
+	x, n := binary.Uvarint(raw)
+	if n <= 0 {
+		d.fail(errors.New("varint overflows a 64-bit integer"))
+		return 0
+	}
+	return x
+}
+
+func (d *graphDecoder) signed() int64 {
+	raw := d.varint()
+	if d.err != nil {
+		return 0
+	}
+	x, n := binary.Varint(raw)
+	if n <= 0 {
+		d.fail(errors.New("varint overflows a 64-bit integer"))
+		return 0
+	}
+	return x
+}
+
+func (d *graphDecoder) int() int {
+	x := d.uint()
+	if x > uint64(maxInt) {
+		d.fail(fmt.Errorf("%v is too large", x))
+		return 0
+	}
+	return int(x)
+}
+
+// Reads a count of the things following. Counts only size allocations up to
+// a limit so a corrupted count fails on running out of data rather than
+// allocating absurdly.
+func (d *graphDecoder) count() int {
+	return d.int()
+}
+
+func capHint(n int) int {
+	if n > 1<<16 {
+		return 1 << 16
+	}
+	return n
+}
+
+// Reads the next n bytes with io.ReadFull. The buffer grows as the bytes
+// arrive (up to capHint at a time) so a corrupted n fails on running out of
+// data.
+func (d *graphDecoder) bytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	d.flush()
+	b := make([]byte, 0, capHint(n))
+	for len(b) < n {
+		chunk := n - len(b)
+		if chunk > 1<<16 {
+			chunk = 1 << 16
+		}
+		b = append(b, make([]byte, chunk)...)
+		in := d.in
+		if d.r != nil {
+			in = d.r
+		}
+		if _, err := io.ReadFull(in, b[len(b)-chunk:]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			d.fail(err)
+			return nil
+		}
+	}
+	if d.checksum {
+		d.crc = crc32.Update(d.crc, crc32.IEEETable, b)
+	}
+	return b
+}
@@ -0,0 +1,107 @@
+package goiso
+
+/*
+  Copyright (c) 2014 Tim Henderson
+  Released under the GNU General Public License version 3.
+
+  This file is part of goiso a wrapper around bliss.
+
+  bliss is free software: you can redistribute it and/or modify
+  it under the terms of the GNU General Public License version 3
+  as published by the Free Software Foundation.
+
+  bliss is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU General Public License for more details.
+
+  You should have received a copy of the GNU General Public License
+  along with goiso.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// Turn the stable label mode on or off. Colors are numbered in the order
+// AddColor first sees their labels so two isomorphic graphs whose labels
+// were added in a different order are colored, and thus canonized,
+// differently. In stable label mode the colors are renumbered by the sorted
+// order of their label strings before every canonization. Canonical, Label,
+// SubGraph.Label and SubGraph.ShortLabel are then comparable across separate
+// graphs. ShortLabel encodes the label strings rather than the color ids in
+// this mode. Set it before extracting any subgraphs.
+func (g *Graph) SetStableLabels(stable bool) {
+	g.stable = stable
+}
+
+// Is the graph in stable label mode? See SetStableLabels.
+func (g *Graph) StableLabels() bool {
+	return g.stable
+}
+
+// Copies V and E replacing each color with the rank of its label among the
+// labels used by V and E. The ranks only depend on the label strings.
+func (g *Graph) stableColors(V Vertices, E Edges) (Vertices, Edges) {
+	rank := make(map[int]int)
+	colors := make([]int, 0, len(g.colors()))
+	for _, v := range V {
+		if _, has := rank[v.Color]; !has {
+			rank[v.Color] = 0
+			colors = append(colors, v.Color)
+		}
+	}
+	for _, e := range E {
+		if _, has := rank[e.Color]; !has {
+			rank[e.Color] = 0
+			colors = append(colors, e.Color)
+		}
+	}
+	sort.Slice(colors, func(i, j int) bool {
+		return g.colors()[colors[i]] < g.colors()[colors[j]]
+	})
+	for r, color := range colors {
+		rank[color] = r
+	}
+	nV := make(Vertices, len(V))
+	for i, v := range V {
+		nV[i] = v
+		nV[i].Color = rank[v.Color]
+	}
+	nE := make(Edges, len(E))
+	for i, e := range E {
+		nE[i] = e
+		nE[i].Color = rank[e.Color]
+	}
+	return nV, nE
+}
+
+// format: (edge count : 4)(vertex count : 4)[(label length : 4)(label)]+
+//         [(src idx : 4)(targ idx : 4)(label length : 4)(label)]+
+func (sg *SubGraph) stableShortLabel() []byte {
+	size := 8 + len(sg.V)*4 + len(sg.E)*12
+	for _, v := range sg.V {
+		size += len(sg.G.colors()[v.Color])
+	}
+	for _, e := range sg.E {
+		size += len(sg.G.colors()[e.Color])
+	}
+	label := make([]byte, 0, size)
+	label = binary.BigEndian.AppendUint32(label, uint32(len(sg.E)))
+	label = binary.BigEndian.AppendUint32(label, uint32(len(sg.V)))
+	for _, v := range sg.V {
+		label = appendLabel(label, sg.G.colors()[v.Color])
+	}
+	for _, e := range sg.E {
+		label = binary.BigEndian.AppendUint32(label, uint32(e.Src))
+		label = binary.BigEndian.AppendUint32(label, uint32(e.Targ))
+		label = appendLabel(label, sg.G.colors()[e.Color])
+	}
+	return label
+}
+
+func appendLabel(buf []byte, label string) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(label)))
+	return append(buf, label...)
+}
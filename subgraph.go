@@ -21,11 +21,11 @@ package goiso
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"log"
-	"runtime/debug"
 	"strings"
 )
 
@@ -61,7 +61,66 @@ func canonSubGraph(g *Graph, V Vertices, E Edges) (sg *SubGraph, canonized bool)
 		sg.vertexIndex[sg.V[0].Id] = &sg.V[0]
 		return sg, true
 	}
-	bMap := bliss.NewMap(len(V), len(E), V.Iterate(), E.Iterate())
+	vord, eord, canonized := g.canonicalPermutation(V, E)
+	return permuteSubGraph(g, V, E, vord, eord), canonized
+}
+
+// The same as canonSubGraph but the search may be abandoned. See
+// Graph.SubGraphContext.
+func canonSubGraphContext(ctx context.Context, g *Graph, V Vertices, E Edges, opts *bliss.Options) (sg *SubGraph, canonized bool, err error) {
+	if len(V) == 1 && len(E) == 0 {
+		sg, canonized = canonSubGraph(g, V, E)
+		return sg, canonized, nil
+	}
+	vord, eord, canonized, err := g.canonicalPermutationContext(ctx, V, E, opts)
+	if err != nil {
+		return nil, false, err
+	}
+	return permuteSubGraph(g, V, E, vord, eord), canonized, nil
+}
+
+// The same as canonSubGraph for many subgraphs. If the graph's
+// Canonicalizer is a BatchCanonicalizer every subgraph which needs a search
+// is canonized in a single batch.
+func canonSubGraphs(g *Graph, Vs []Vertices, Es []Edges) (sgs []*SubGraph, canonized []bool) {
+	sgs = make([]*SubGraph, len(Vs))
+	canonized = make([]bool, len(Vs))
+	batcher, ok := g.Canonicalizer().(BatchCanonicalizer)
+	if !ok {
+		for i := range Vs {
+			sgs[i], canonized[i] = canonSubGraph(g, Vs[i], Es[i])
+		}
+		return sgs, canonized
+	}
+	bVs := make([]Vertices, 0, len(Vs))
+	bEs := make([]Edges, 0, len(Vs))
+	idxs := make([]int, 0, len(Vs))
+	for i := range Vs {
+		if len(Vs[i]) == 1 && len(Es[i]) == 0 {
+			sgs[i], canonized[i] = canonSubGraph(g, Vs[i], Es[i])
+			continue
+		}
+		bV, bE := Vs[i], Es[i]
+		if g.stable {
+			bV, bE = g.stableColors(bV, bE)
+		}
+		bVs = append(bVs, bV)
+		bEs = append(bEs, bE)
+		idxs = append(idxs, i)
+	}
+	if len(idxs) == 0 {
+		return sgs, canonized
+	}
+	vords, eords, cs := batcher.CanonicalPermutations(bVs, bEs)
+	for j, i := range idxs {
+		sgs[i] = permuteSubGraph(g, Vs[i], Es[i], vords[j], eords[j])
+		canonized[i] = cs[j]
+	}
+	return sgs, canonized
+}
+
+// Constructs the subgraph of V and E reordered by vord and eord.
+func permuteSubGraph(g *Graph, V Vertices, E Edges, vord, eord []int) (sg *SubGraph) {
 	sg = &SubGraph{
 		G:           g,
 		V:           make([]Vertex, len(V)),
@@ -77,7 +136,6 @@ func canonSubGraph(g *Graph, V Vertices, E Edges) (sg *SubGraph, canonized bool)
 	for i := range sg.Parents {
 		sg.Parents[i] = make([]*Edge, 0, 5)
 	}
-	vord, eord, canonized := bMap.CanonicalPermutation()
 	// i is the old vid, j is the new vid
 	for i, j := range vord {
 		sg.V[j] = (V)[i].Copy(j)
@@ -92,7 +150,7 @@ func canonSubGraph(g *Graph, V Vertices, E Edges) (sg *SubGraph, canonized bool)
 		idArc := ColoredArc{Arc{sg.V[sg.E[i].Src].Id, sg.V[sg.E[i].Targ].Id}, sg.E[i].Color}
 		sg.edgeIndex[idArc] = &sg.E[i]
 	}
-	return sg, canonized
+	return sg
 }
 
 // This is a useful method for finding out if the subgraph has a
@@ -276,6 +334,20 @@ func (sg *SubGraph) RemoveEdge(edgeIdx int) (nsg *SubGraph, canonized bool) {
 	return canonSubGraph(sg.G, V, E)
 }
 
+// Computes which vertices and edges of the subgraph are structurally
+// equivalent. Read the returned variables as:
+//
+//   - V[vertex.Idx] -> the smallest Idx of a vertex in the same orbit
+//   - E[edge.Idx] -> the smallest Idx of an edge in the same orbit
+//
+// The indices are into sg.V and sg.E (not the parent graph).
+func (sg *SubGraph) Orbits() (V, E []int) {
+	if len(sg.V) == 0 {
+		return []int{}, []int{}
+	}
+	return bliss.NewMap(len(sg.V), len(sg.E), sg.V.Iterate(), sg.E.Iterate()).Orbits()
+}
+
 func (sg *SubGraph) Connected() bool {
 	pop := func(stack []int) (int, []int) {
 		idx := stack[len(stack)-1]
@@ -399,82 +471,15 @@ func (sg *SubGraph) Lattice() *Lattice {
 	return &Lattice{lattice, edges}
 }
 
-// See SubGraph.Serialize for the format
+// Decodes a subgraph written by SubGraph.Serialize or SubGraph.Encode. It is
+// only kept for compatibility: it panics on malformed or corrupted input.
+// New code should call DecodeSubGraph which returns an error instead.
 func DeserializeSubGraph(g *Graph, bytes []byte) *SubGraph {
-	defer func() {
-		if e := recover(); e != nil {
-			log.Println(string(debug.Stack()))
-			log.Println(bytes)
-			panic(e)
-		}
-	}()
-	mark := binary.LittleEndian.Uint32(bytes[0:4])
-	lenV := binary.LittleEndian.Uint32(bytes[4:8])
-	lenE := binary.LittleEndian.Uint32(bytes[8:12])
-	if mark != 0xaaaaaaaa {
-		log.Println("not a serialized subgraph")
-		log.Println("value>", bytes)
-		panic(fmt.Errorf("Not a serialized subgraph"))
-	}
-	off := 12
-	V := make([]Vertex, lenV)
-	E := make([]Edge, lenE)
-	kids := make([][]*Edge, len(V))
-	parents := make([][]*Edge, len(V))
-	vertexIndex := make(map[int]*Vertex, len(V))
-	edgeIndex := make(map[ColoredArc]*Edge, len(E))
-	for i := range kids {
-		kids[i] = make([]*Edge, 0, 5)
-	}
-	for i := range parents {
-		parents[i] = make([]*Edge, 0, 5)
-	}
-	for i := 0; i < int(lenV); i++ {
-		s := off + i*4
-		e := s + 4
-		id := int(binary.LittleEndian.Uint32(bytes[s:e]))
-		v := Vertex{
-			Idx:   i,
-			Id:    id,
-			Color: g.V[id].Color,
-		}
-		V[i] = v
-		vertexIndex[v.Id] = &V[i]
-	}
-	off += len(V) * 4
-	for i := 0; i < int(lenE); i++ {
-		s := off + i*12
-		e := s + 4
-		src := int(binary.LittleEndian.Uint32(bytes[s:e]))
-		s += 4
-		e += 4
-		targ := int(binary.LittleEndian.Uint32(bytes[s:e]))
-		s += 4
-		e += 4
-		color := int(binary.LittleEndian.Uint32(bytes[s:e]))
-		edge := Edge{
-			Arc: Arc{
-				Src:  src,
-				Targ: targ,
-			},
-			Idx:   i,
-			Color: color,
-		}
-		E[i] = edge
-		kids[E[i].Src] = append(kids[E[i].Src], &E[i])
-		parents[E[i].Targ] = append(parents[E[i].Targ], &E[i])
-		idArc := ColoredArc{Arc{V[E[i].Src].Id, V[E[i].Targ].Id}, E[i].Color}
-		edgeIndex[idArc] = &E[i]
-	}
-	return &SubGraph{
-		G:           g,
-		V:           V,
-		E:           E,
-		Kids:        kids,
-		Parents:     parents,
-		edgeIndex:   edgeIndex,
-		vertexIndex: vertexIndex,
+	sg, err := DecodeSubGraph(g, bytes)
+	if err != nil {
+		panic(err)
 	}
+	return sg
 }
 
 // format: (vertex count : 4)(edge count : 4)(vertex id : 4)+[edge (src idx : 4)(targ idx : 4)(label color : 4)]+
@@ -482,6 +487,9 @@ func DeserializeSubGraph(g *Graph, bytes []byte) *SubGraph {
 // vertices are in idx order.
 // edges are in idx order.
 // the order is the canonical order.
+//
+// This is the v1 format. It truncates ids and colors past 2^32 and has no
+// checksum, prefer Encode.
 func (sg *SubGraph) Serialize() []byte {
 	bytes := make([]byte, 12+len(sg.V)*4+len(sg.E)*12)
 	binary.LittleEndian.PutUint32(bytes[0:4], uint32(0xaaaaaaaa))
@@ -508,7 +516,14 @@ func (sg *SubGraph) Serialize() []byte {
 	return bytes
 }
 
+// A compact binary label, unique after canonicalization. It encodes the
+// color ids so it is only comparable between subgraphs of the same graph
+// unless the graph is in stable label mode (see Graph.SetStableLabels) in
+// which case the label strings are encoded instead.
 func (sg *SubGraph) ShortLabel() []byte {
+	if sg.G != nil && sg.G.stable {
+		return sg.stableShortLabel()
+	}
 	size := 8 + len(sg.V)*4 + len(sg.E)*12
 	label := make([]byte, size)
 	binary.BigEndian.PutUint32(label[0:4], uint32(len(sg.E)))
@@ -543,7 +558,7 @@ func (sg *SubGraph) Label() string {
 		V = append(V, fmt.Sprintf(
 			"(%v:%v)",
 			v.Idx,
-			sg.G.Colors[v.Color],
+			sg.G.colors()[v.Color],
 		))
 	}
 	for _, e := range sg.E {
@@ -551,7 +566,7 @@ func (sg *SubGraph) Label() string {
 			"[%v->%v:%v]",
 			e.Src,
 			e.Targ,
-			sg.G.Colors[e.Color],
+			sg.G.colors()[e.Color],
 		))
 	}
 	return fmt.Sprintf("%v:%v%v%v", len(sg.E), len(sg.V), strings.Join(V, ""), strings.Join(E, ""))
@@ -567,14 +582,11 @@ func (sg *SubGraph) StringWithAttrs(attrs map[int]map[string]interface{}) string
 	V := make([]string, 0, len(sg.V))
 	E := make([]string, 0, len(sg.E))
 	safeStr := func(i interface{}) string {
-		s := fmt.Sprint(i)
-		s = strings.Replace(s, "\n", "\\n", -1)
-		s = strings.Replace(s, "\"", "\\\"", -1)
-		return s
+		return dotEscape(fmt.Sprint(i))
 	}
 	renderAttrs := func(v *Vertex) string {
 		a := attrs[v.Id]
-		label := sg.G.Colors[v.Color]
+		label := sg.G.colors()[v.Color]
 		strs := make([]string, 0, len(a)+1)
 		strs = append(strs, fmt.Sprintf(`idx="%v"`, v.Id))
 		if line, has := a["start_line"]; has {
@@ -602,7 +614,7 @@ func (sg *SubGraph) StringWithAttrs(attrs map[int]map[string]interface{}) string
 			"%v -> %v [label=\"%v\"];",
 			sg.G.V[sg.V[e.Src].Id].Id,
 			sg.G.V[sg.V[e.Targ].Id].Id,
-			sg.G.Colors[e.Color],
+			dotEscape(sg.G.colors()[e.Color]),
 		))
 	}
 	return fmt.Sprintf(
@@ -627,7 +639,7 @@ func (sg *SubGraph) VEG(attrs map[int]map[string]interface{}) []byte {
 func (sg *SubGraph) vegVertex(v *Vertex, attrs map[string]interface{}) []byte {
 	obj := make(JsonObject)
 	obj["id"] = sg.G.V[v.Id].Id
-	obj["label"] = sg.G.Colors[v.Color]
+	obj["label"] = sg.G.colors()[v.Color]
 	for k, v := range attrs {
 		obj[k] = v
 	}
@@ -639,7 +651,7 @@ func (sg *SubGraph) vegEdge(e *Edge) []byte {
 	obj := make(JsonObject)
 	obj["src"] = sg.G.V[sg.V[e.Src].Id].Id
 	obj["targ"] = sg.G.V[sg.V[e.Targ].Id].Id
-	obj["label"] = sg.G.Colors[e.Color]
+	obj["label"] = sg.G.colors()[e.Color]
 	j := renderJson(obj)
 	return bytes.Join([][]byte{[]byte("edge"), j}, []byte("\t"))
 }
@@ -0,0 +1,227 @@
+package goiso
+
+/*
+  Copyright (c) 2014 Tim Henderson
+  Released under the GNU General Public License version 3.
+
+  This file is part of goiso a wrapper around bliss.
+
+  bliss is free software: you can redistribute it and/or modify
+  it under the terms of the GNU General Public License version 3
+  as published by the Free Software Foundation.
+
+  bliss is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU General Public License for more details.
+
+  You should have received a copy of the GNU General Public License
+  along with goiso.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+import (
+	"fmt"
+)
+
+// How the support of a pattern is computed from its embeddings in a single
+// graph.
+type SupportMeasure int
+
+const (
+	// The number of embeddings. This is not anti-monotone: a pattern may
+	// have more embeddings than its subpatterns.
+	EmbeddingCount SupportMeasure = iota
+	// Minimum image based support: the least number of distinct graph
+	// vertices any pattern vertex orbit is mapped onto.
+	MNI
+	// The size of a maximum set of embeddings which share no vertices.
+	MIS
+	// The size of a maximum set of embeddings which do not harmfully overlap
+	// (Fiedler and Borgelt 2007).
+	HarmfulOverlap
+)
+
+func (m SupportMeasure) String() string {
+	switch m {
+	case EmbeddingCount:
+		return "embedding count"
+	case MNI:
+		return "MNI"
+	case MIS:
+		return "MIS"
+	case HarmfulOverlap:
+		return "harmful overlap"
+	}
+	return fmt.Sprintf("SupportMeasure(%d)", int(m))
+}
+
+// Computes the support of the pattern from its embeddings. Each embedding
+// maps the pattern's vertices onto the graph's:
+//
+//     embedding[pattern vertex Idx] -> graph vertex Idx
+//
+// (this is what Miner and Matcher produce). The measures account for the
+// pattern's automorphisms through its orbits so it does not matter whether
+// the embeddings include every symmetric copy of an occurrence or just one.
+// MIS and HarmfulOverlap solve maximum independent set exactly which can
+// take exponential time for many heavily overlapping embeddings.
+func Support(pattern *SubGraph, embeddings [][]int, measure SupportMeasure) int {
+	switch measure {
+	case EmbeddingCount:
+		return len(embeddings)
+	case MNI:
+		return mni(pattern, embeddings)
+	case MIS:
+		return maxIndependentSet(overlaps(embeddings, vertexOverlap))
+	case HarmfulOverlap:
+		orbits, _ := pattern.Orbits()
+		return maxIndependentSet(overlaps(embeddings, func(a, b []int) bool {
+			return harmfulOverlap(orbits, a, b)
+		}))
+	}
+	panic(fmt.Errorf("unknown support measure %v", measure))
+}
+
+func mni(pattern *SubGraph, embeddings [][]int) int {
+	if len(embeddings) == 0 {
+		return 0
+	}
+	orbits, _ := pattern.Orbits()
+	images := make(map[int]map[int]bool)
+	for _, embedding := range embeddings {
+		for v, vid := range embedding {
+			orbit := images[orbits[v]]
+			if orbit == nil {
+				orbit = make(map[int]bool)
+				images[orbits[v]] = orbit
+			}
+			orbit[vid] = true
+		}
+	}
+	support := -1
+	for _, orbit := range images {
+		if support < 0 || len(orbit) < support {
+			support = len(orbit)
+		}
+	}
+	if support < 0 {
+		// the empty pattern
+		return 0
+	}
+	return support
+}
+
+// Builds the overlap graph of the embeddings.
+func overlaps(embeddings [][]int, overlap func(a, b []int) bool) [][]int {
+	adj := make([][]int, len(embeddings))
+	for i := range embeddings {
+		for j := i + 1; j < len(embeddings); j++ {
+			if overlap(embeddings[i], embeddings[j]) {
+				adj[i] = append(adj[i], j)
+				adj[j] = append(adj[j], i)
+			}
+		}
+	}
+	return adj
+}
+
+func vertexOverlap(a, b []int) bool {
+	for _, u := range a {
+		for _, v := range b {
+			if u == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Two embeddings overlap harmfully if some pattern vertex orbit has an image
+// in their shared vertices under both. With the orbit standing in for every
+// automorphic copy of b this is Fiedler and Borgelt's condition: a pattern
+// vertex v with a(v) and b(v) both shared.
+func harmfulOverlap(orbits, a, b []int) bool {
+	shared := make(map[int]bool)
+	for _, u := range a {
+		for _, v := range b {
+			if u == v {
+				shared[u] = true
+			}
+		}
+	}
+	if len(shared) == 0 {
+		return false
+	}
+	inA := make(map[int]bool)
+	for v, vid := range a {
+		if shared[vid] {
+			inA[orbits[v]] = true
+		}
+	}
+	for v, vid := range b {
+		if shared[vid] && inA[orbits[v]] {
+			return true
+		}
+	}
+	return false
+}
+
+// The size of a maximum independent set of the graph given as adjacency
+// lists. Branches on the vertex of greatest degree after taking every vertex
+// of degree zero or one, which is always safe.
+func maxIndependentSet(adj [][]int) int {
+	alive := make([]bool, len(adj))
+	for i := range alive {
+		alive[i] = true
+	}
+	return mis(adj, alive)
+}
+
+func mis(adj [][]int, alive []bool) int {
+	degree := func(v int) int {
+		d := 0
+		for _, u := range adj[v] {
+			if alive[u] {
+				d++
+			}
+		}
+		return d
+	}
+	take := func(v int) {
+		alive[v] = false
+		for _, u := range adj[v] {
+			alive[u] = false
+		}
+	}
+	size := 0
+	for reduced := true; reduced; {
+		reduced = false
+		for v := range adj {
+			if alive[v] && degree(v) <= 1 {
+				take(v)
+				size++
+				reduced = true
+			}
+		}
+	}
+	branch, most := -1, -1
+	for v := range adj {
+		if alive[v] {
+			if d := degree(v); d > most {
+				branch, most = v, d
+			}
+		}
+	}
+	if branch < 0 {
+		return size
+	}
+	without := make([]bool, len(alive))
+	copy(without, alive)
+	without[branch] = false
+	take(branch)
+	with := 1 + mis(adj, alive)
+	if w := mis(adj, without); w > with {
+		with = w
+	}
+	return size + with
+}
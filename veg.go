@@ -0,0 +1,138 @@
+package goiso
+
+/*
+  Copyright (c) 2014 Tim Henderson
+  Released under the GNU General Public License version 3.
+
+  This file is part of goiso a wrapper around bliss.
+
+  bliss is free software: you can redistribute it and/or modify
+  it under the terms of the GNU General Public License version 3
+  as published by the Free Software Foundation.
+
+  bliss is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU General Public License for more details.
+
+  You should have received a copy of the GNU General Public License
+  along with goiso.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Reads a graph in the VEG format written by SubGraph.VEG. Each line is
+// either
+//
+//     vertex\t{"id": <int>, "label": <string>, ...}
+//     edge\t{"src": <vertex id>, "targ": <vertex id>, "label": <string>}
+//
+// Vertices keep their ids (as Vertex.Id) and are added in the order they are
+// read. Edges must come after the vertices they connect. Blank lines are
+// skipped. Use LoadVEGWithAttrs to keep the other attributes of the vertices.
+func LoadVEG(r io.Reader) (*Graph, error) {
+	g, _, err := LoadVEGWithAttrs(r)
+	return g, err
+}
+
+// The same as LoadVEG but also returns the vertex attributes other than id
+// and label keyed by vertex Idx. This is the attrs argument of SubGraph.VEG
+// and SubGraph.StringWithAttrs for a SubGraph of the loaded graph. Numbers
+// are json.Numbers.
+func LoadVEGWithAttrs(r io.Reader) (*Graph, map[int]map[string]interface{}, error) {
+	g := NewGraph(100, 100)
+	attrs := make(map[int]map[string]interface{})
+	vids := make(map[int64]int)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		errorf := func(format string, args ...interface{}) error {
+			return fmt.Errorf("goiso: VEG: error in line %v: %v", lineNum, fmt.Sprintf(format, args...))
+		}
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		split := bytes.IndexByte(line, '\t')
+		if split < 0 {
+			return nil, nil, errorf("expected a tab after vertex or edge")
+		}
+		var obj map[string]interface{}
+		data := line[split+1:]
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&obj); err != nil {
+			return nil, nil, errorf("%v", err)
+		}
+		if rest := bytes.TrimSpace(data[dec.InputOffset():]); len(rest) > 0 {
+			return nil, nil, errorf("unexpected %q after the object", rest)
+		}
+		label, ok := obj["label"].(string)
+		if !ok {
+			return nil, nil, errorf("expected a string label")
+		}
+		integer := func(name string) (int64, error) {
+			n, ok := obj[name].(json.Number)
+			if !ok {
+				return 0, errorf("expected an integer %v", name)
+			}
+			i, err := n.Int64()
+			if err != nil {
+				return 0, errorf("expected an integer %v got %v", name, n)
+			}
+			return i, nil
+		}
+		vertex := func(name string) (*Vertex, error) {
+			id, err := integer(name)
+			if err != nil {
+				return nil, err
+			}
+			idx, has := vids[id]
+			if !has {
+				return nil, errorf("%v %v is not a vertex", name, id)
+			}
+			return &g.V[idx], nil
+		}
+		switch kind := string(bytes.TrimSpace(line[:split])); kind {
+		case "vertex":
+			id, err := integer("id")
+			if err != nil {
+				return nil, nil, err
+			}
+			if _, has := vids[id]; has {
+				return nil, nil, errorf("duplicate vertex id %v", id)
+			}
+			v := g.AddVertex(int(id), label)
+			vids[id] = v.Idx
+			delete(obj, "id")
+			delete(obj, "label")
+			if len(obj) > 0 {
+				attrs[v.Idx] = obj
+			}
+		case "edge":
+			src, err := vertex("src")
+			if err != nil {
+				return nil, nil, err
+			}
+			targ, err := vertex("targ")
+			if err != nil {
+				return nil, nil, err
+			}
+			g.AddEdge(src, targ, label)
+		default:
+			return nil, nil, errorf("unknown line type %q", kind)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("goiso: VEG: error in line %v: %v", lineNum+1, err)
+	}
+	return &g, attrs, nil
+}